@@ -2,6 +2,7 @@ package chatgpt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,7 +10,10 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/luizhenriquees/go-http-rpa/engine"
 	"github.com/luizhenriquees/go-http-rpa/entity"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
 )
 
 const (
@@ -40,23 +44,41 @@ type Response struct {
 }
 
 type Agent struct {
-	apiKey string
+	apiKey secret.Secret
+	client *httprequest.Client
+	logger engine.Logger
 }
 
-func NewAgent() *Agent {
-	return &Agent{
-		apiKey: os.Getenv("CHATGPT_API_KEY"),
+// Option configures the Agent
+type Option func(*Agent)
+
+// WithClient sends the requests to OpenAI with the client instead of the http_request default client
+func WithClient(client *httprequest.Client) Option {
+	return func(a *Agent) {
+		a.client = client
 	}
 }
 
+func NewAgent(opts ...Option) *Agent {
+	agent := &Agent{
+		apiKey: secret.New(os.Getenv("CHATGPT_API_KEY")),
+		logger: engine.NewLogger("ChatGPT"),
+	}
+	for _, opt := range opts {
+		opt(agent)
+	}
+	return agent
+}
+
 // GetAnswerIndex sends the question to ChatGPT and gets the answer index
-func (a *Agent) GetAnswerIndex(question entity.Question) (int, error) {
-	if a.apiKey == "" {
+func (a *Agent) GetAnswerIndex(ctx context.Context, question entity.Question) (int, error) {
+	if a.apiKey.IsZero() {
 		return 0, fmt.Errorf("CHATGPT_API_KEY environment variable not set")
 	}
 
+	logger := engine.ContextLogger(ctx, a.logger)
 	prompt := a.buildPrompt(question)
-	fmt.Println("Sending question to AI model...")
+	logger.Info("Sending question to AI model...")
 
 	reqBody := Request{
 		Model: openAIModel,
@@ -78,16 +100,20 @@ func (a *Agent) GetAnswerIndex(question entity.Question) (int, error) {
 		return 0, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", openAIEndpoint, bytes.NewBuffer(reqJSON))
+	req, err := http.NewRequestWithContext(ctx, "POST", openAIEndpoint, bytes.NewBuffer(reqJSON))
 	if err != nil {
 		return 0, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set("Authorization", "Bearer "+a.apiKey.Reveal())
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// The request goes through the client rate limit, connections and middleware, but not through the dry
+	// run nor the cassette of the run: asking the model changes nothing on the website.
+	if err := a.client.Wait(ctx, req.URL.Host); err != nil {
+		return 0, fmt.Errorf("error sending request to OpenAI: %w", err)
+	}
+	resp, err := a.client.HTTPClient().Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("error sending request to OpenAI: %w", err)
 	}
@@ -99,7 +125,7 @@ func (a *Agent) GetAnswerIndex(question entity.Question) (int, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("OpenAI API returned error: %s", string(body))
+		return 0, fmt.Errorf("OpenAI API returned error: %s", secret.Redact(string(body)))
 	}
 
 	var chatResponse Response
@@ -113,7 +139,7 @@ func (a *Agent) GetAnswerIndex(question entity.Question) (int, error) {
 
 	// Extract the answer index from response
 	answerContent := chatResponse.Choices[0].Message.Content
-	fmt.Printf("AI response: %s\n", answerContent)
+	logger.Info("AI response: %s", answerContent)
 
 	answerIndex, err := strconv.Atoi(answerContent)
 	if err != nil {
@@ -126,7 +152,7 @@ func (a *Agent) GetAnswerIndex(question entity.Question) (int, error) {
 	}
 
 	if answerIndex < 0 || answerIndex >= len(question.Options) {
-		fmt.Printf("Warning: AI returned invalid index %d, defaulting to 0\n", answerIndex)
+		logger.Warn("AI returned invalid index %d, defaulting to 0", answerIndex)
 		answerIndex = 0
 	}
 	return answerIndex, nil
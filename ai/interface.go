@@ -1,7 +1,11 @@
 package ai
 
-import "github.com/luizhenriquees/go-http-rpa/entity"
+import (
+	"context"
+
+	"github.com/luizhenriquees/go-http-rpa/entity"
+)
 
 type ExamAssistant interface {
-	GetAnswerIndex(question entity.Question) (int, error)
+	GetAnswerIndex(ctx context.Context, question entity.Question) (int, error)
 }
@@ -1,24 +1,27 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"log"
 	"os"
 
-	"github.com/joho/godotenv"
-
 	"github.com/luizhenriquees/go-http-rpa/ai/chatgpt"
+	"github.com/luizhenriquees/go-http-rpa/cmd/internal/setup"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
 	"github.com/luizhenriquees/go-http-rpa/usecase"
 )
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	flags := setup.RegisterFlags("answer_exam")
+	flag.Parse()
+
+	if err := setup.LoadEnv(); err != nil {
+		log.Fatal(err)
 	}
-	headers := make(map[string]string)
+	headers := make(httprequest.Headers)
 	headers["Content-Type"] = "application/json"
-	headers["X-Authorization"] = os.Getenv("WEBSITE_TOKEN")
+	headers.SetSecret("X-Authorization", secret.New(os.Getenv("WEBSITE_TOKEN")))
 
 	quizInput := usecase.CourseInput{
 		BaseUrl:   os.Getenv("WEBSITE_URL"),
@@ -26,11 +29,17 @@ func main() {
 		Headers:   headers,
 	}
 
-	aiAssistant := chatgpt.NewAgent()
-	uc := usecase.NewAnswerExamRpa(aiAssistant)
-	err = uc.Execute(quizInput)
+	ctx, run, err := setup.Start("answer_exam", flags)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("RPA executed successfully")
+	// The AI agent talks to another API, it never needs the website session
+	aiAssistant := chatgpt.NewAgent(chatgpt.WithClient(run.BaseClient))
+	uc := usecase.NewAnswerExamRpa(aiAssistant)
+	uc.SetDryRun(run.DryRun)
+	uc.SetClient(run.Client)
+	if run.Login != nil {
+		uc.SetLogin(*run.Login)
+	}
+	run.Finish(uc.Execute(ctx, quizInput))
 }
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	"github.com/luizhenriquees/go-http-rpa/fakelms"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address the fake LMS listens on")
+	seedPath := flag.String("seed", "", "JSON file with the courses and quizzes to serve; a small built-in seed when empty")
+	token := flag.String("token", "", "X-Authorization value required on every request; any value is accepted when empty, unless -username or -password is set")
+	username := flag.String("username", "", "username accepted by POST /api/login, which is only served with credentials")
+	password := flag.String("password", "", "password accepted by POST /api/login")
+	flag.Parse()
+
+	logConfig, err := engine.LogConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	engine.ConfigureLogging(logConfig)
+
+	seed := fakelms.DefaultSeed()
+	if *seedPath != "" {
+		if seed, err = fakelms.LoadSeed(*seedPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	lms, err := fakelms.NewServer(seed, fakelms.WithToken(*token), fakelms.WithCredentials(*username, *password))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *addr, Handler: lms, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Fake LMS listening", "website_url", "http://"+*addr+"/")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	correct, total := lms.Score()
+	slog.Info("Fake LMS stopped", "answers", total, "correct", correct)
+}
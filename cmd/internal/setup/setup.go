@@ -0,0 +1,145 @@
+// Package setup holds the bootstrap shared by the commands: environment, logging, redaction, HTTP client,
+// session, auth, cassette, dry run and run report.
+package setup
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/report"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// Flags are the command line flags shared by the commands
+type Flags struct {
+	DryRun   bool
+	Fixtures string
+	Report   string
+}
+
+// RegisterFlags registers the -dry-run, -fixtures and -report flags, the report defaulting to
+// reports/<name>.json
+func RegisterFlags(name string) *Flags {
+	flags := &Flags{}
+	flag.BoolVar(&flags.DryRun, "dry-run", false, "log the requests that would change the website instead of sending them")
+	flag.StringVar(&flags.Fixtures, "fixtures", "", "JSON fixtures answering the requests of a dry run")
+	flag.StringVar(&flags.Report, "report", "reports/"+name+".json", "JSON run report, also rendered as Markdown next to it; empty disables it")
+	return flags
+}
+
+// LoadEnv loads the .env file, then configures the logging and the redaction from the environment
+func LoadEnv() error {
+	if err := godotenv.Load(); err != nil {
+		return errors.New("error loading .env file")
+	}
+	logConfig, err := engine.LogConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	engine.ConfigureLogging(logConfig)
+	redactConfig, err := secret.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	secret.Configure(redactConfig)
+	return nil
+}
+
+// Run holds what a command shares with its RPA
+type Run struct {
+	// Client sends the requests to the website, with the session and the token refresh when configured
+	Client *httprequest.Client
+	// BaseClient shares the connections and the rate limit of Client, without the website session, for
+	// the other APIs
+	BaseClient *httprequest.Client
+	// DryRun is set by the -dry-run flag
+	DryRun *httprequest.DryRun
+	// Login is the login of the website, nil when LOGIN_URL is not set. It is the first step of the RPA.
+	Login    *engine.LoginConfig
+	Recorder *report.Recorder
+
+	cassette   *httprequest.Cassette
+	reportPath string
+	stop       context.CancelFunc
+}
+
+// Start sets the default retry policy and builds the client of the run from the environment. The returned
+// context is cancelled on interrupt and carries the cassette and the report recorder named name.
+func Start(name string, flags *Flags) (context.Context, *Run, error) {
+	retryPolicy, err := httprequest.RetryPolicyFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	httprequest.SetDefaultRetryPolicy(retryPolicy)
+	clientConfig, err := httprequest.ClientConfigFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	run := &Run{reportPath: flags.Report}
+	if run.BaseClient, err = httprequest.NewClient(clientConfig); err != nil {
+		return nil, nil, err
+	}
+	run.Client = run.BaseClient
+	if run.Login, err = engine.LoginConfigFromEnv(); err != nil {
+		return nil, nil, err
+	}
+	if run.Login != nil {
+		run.Client = run.Client.WithSession(httprequest.NewSession(""))
+	}
+	authConfig, err := httprequest.OAuthConfigFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+	if authConfig != nil {
+		provider, err := httprequest.NewOAuthProvider(*authConfig, run.Client)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid auth config: %w", err)
+		}
+		run.Client = run.Client.WithAuth(provider, authConfig.Hosts...)
+	}
+	if run.cassette, err = httprequest.CassetteFromEnv(); err != nil {
+		return nil, nil, err
+	}
+	if flags.DryRun {
+		if run.DryRun, err = httprequest.NewDryRun(flags.Fixtures); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	run.stop = stop
+	if run.cassette != nil {
+		ctx = httprequest.WithCassette(ctx, run.cassette)
+	}
+	run.Recorder = report.NewRecorder(name)
+	ctx = report.WithRecorder(ctx, run.Recorder)
+	return ctx, run, nil
+}
+
+// Finish writes the run report and the cassette, then exits with err when the run failed
+func (r *Run) Finish(err error) {
+	r.stop()
+	if reportErr := r.Recorder.Save(r.reportPath, err); reportErr != nil {
+		slog.Error("Could not write the run report", "error", reportErr)
+	}
+	if r.cassette != nil {
+		if cassetteErr := r.cassette.Save(); cassetteErr != nil {
+			slog.Error("Could not write the HTTP cassette", "error", cassetteErr)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.Info("RPA executed successfully")
+}
@@ -1,24 +1,36 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"log"
 
-	"github.com/joho/godotenv"
-
+	"github.com/luizhenriquees/go-http-rpa/cmd/internal/setup"
+	"github.com/luizhenriquees/go-http-rpa/engine"
 	rpaquiz "github.com/luizhenriquees/go-http-rpa/rpa_quiz"
 )
 
 func main() {
-	err := godotenv.Load()
+	resume := flag.Bool("resume", false, "resume the interrupted run recorded in the journal")
+	journalPath := flag.String("journal", ".rpa_quiz.journal.json", "file where the run progress is recorded")
+	flags := setup.RegisterFlags("rpa_quiz")
+	flag.Parse()
+
+	if err := setup.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
+	journal, err := engine.OpenJournal(*journalPath, *resume)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal(err)
 	}
-
-	quizRpa := rpaquiz.NewRpaQuiz()
-	if err = quizRpa.Execute(); err != nil {
+	ctx, run, err := setup.Start("rpa_quiz", flags)
+	if err != nil {
 		log.Fatal(err)
 	}
-
-	fmt.Println("RPA executed successfully")
+	opts := []rpaquiz.Option{rpaquiz.WithJournal(journal), rpaquiz.WithDryRun(run.DryRun), rpaquiz.WithClient(run.Client)}
+	if run.Login != nil {
+		opts = append(opts, rpaquiz.WithLogin(*run.Login))
+	}
+	quizRpa := rpaquiz.NewRpaQuiz(opts...)
+	quizRpa.AddListener(run.Recorder)
+	run.Finish(quizRpa.Execute(ctx))
 }
@@ -1,33 +1,47 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"log"
 	"os"
 
-	"github.com/joho/godotenv"
-
+	"github.com/luizhenriquees/go-http-rpa/cmd/internal/setup"
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
 	"github.com/luizhenriquees/go-http-rpa/usecase"
 )
 
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	resume := flag.Bool("resume", false, "resume the interrupted run recorded in the journal")
+	journalPath := flag.String("journal", ".watch_course.journal.json", "file where the run progress is recorded")
+	flags := setup.RegisterFlags("watch_course")
+	flag.Parse()
+
+	if err := setup.LoadEnv(); err != nil {
+		log.Fatal(err)
 	}
-	headers := make(map[string]string)
+	headers := make(httprequest.Headers)
 	headers["Content-Type"] = "application/json"
-	headers["X-Authorization"] = os.Getenv("WEBSITE_TOKEN")
+	headers.SetSecret("X-Authorization", secret.New(os.Getenv("WEBSITE_TOKEN")))
 
 	quizInput := usecase.CourseInput{
 		BaseUrl:   os.Getenv("WEBSITE_URL"),
 		CourseIDs: []int{}, // Add your course ids here. Ex: []int{1, 2, 3}. If not provided it will fetch all available.
 		Headers:   headers,
 	}
-	uc := usecase.NewWatchCourseRpa()
-	err = uc.Execute(quizInput)
+
+	journal, err := engine.OpenJournal(*journalPath, *resume)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("RPA executed successfully")
+	ctx, run, err := setup.Start("watch_course", flags)
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := []usecase.WatchCourseOption{usecase.WithJournal(journal), usecase.WithDryRun(run.DryRun), usecase.WithClient(run.Client)}
+	if run.Login != nil {
+		opts = append(opts, usecase.WithLogin(*run.Login))
+	}
+	run.Finish(usecase.NewWatchCourseRpa(opts...).Execute(ctx, quizInput))
 }
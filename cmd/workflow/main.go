@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/luizhenriquees/go-http-rpa/cmd/internal/setup"
+	"github.com/luizhenriquees/go-http-rpa/workflow"
+)
+
+func main() {
+	file := flag.String("file", "workflows/quiz.yaml", "path to the workflow file (YAML or JSON)")
+	flags := setup.RegisterFlags("workflow")
+	flag.Parse()
+
+	if err := setup.LoadEnv(); err != nil {
+		log.Fatal(err)
+	}
+	def, err := workflow.Load(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rpa, err := workflow.Compile(def)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, run, err := setup.Start(def.Name, flags)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rpa.AddListener(run.Recorder)
+	rpa.SetDryRun(run.DryRun)
+	rpa.SetClient(run.Client)
+	if run.Login != nil {
+		rpa.SetLogin(*run.Login)
+	}
+	run.Finish(rpa.Execute(ctx))
+}
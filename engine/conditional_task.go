@@ -0,0 +1,228 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+var (
+	ErrNoPredicate = errors.New("conditional task without predicate")
+	ErrNoBranch    = errors.New("conditional task without branch")
+)
+
+// Predicate evaluates a condition over the parameters
+type Predicate func(params *Parameters) (bool, error)
+
+// Selector evaluates which case of a SwitchTask runs
+type Selector func(params *Parameters) (string, error)
+
+// IfTask runs the then task when its predicate holds and the else task, when set, otherwise
+type IfTask struct {
+	name      string
+	params    *Parameters
+	predicate Predicate
+	then      Task
+	otherwise Task
+	Logger    Logger
+}
+
+// NewIfTask creates a task running then when the predicate holds over params
+func NewIfTask(name string, params *Parameters, predicate Predicate, then Task) *IfTask {
+	return &IfTask{
+		name:      name,
+		params:    params,
+		predicate: predicate,
+		then:      then,
+		Logger:    &DefaultLogger{prefix: fmt.Sprintf("If Task - %s", name)},
+	}
+}
+
+// Else sets the task executed when the predicate does not hold
+func (t *IfTask) Else(task Task) *IfTask {
+	t.otherwise = task
+	return t
+}
+
+func (t *IfTask) Name() string {
+	return t.name
+}
+
+func (t *IfTask) Validate() error {
+	if t.predicate == nil {
+		return ErrNoPredicate
+	}
+	if t.then == nil {
+		return ErrNoBranch
+	}
+	return nil
+}
+
+// Execute evaluates the predicate and runs the matching branch. The branch is validated only once chosen,
+// since the parameters it requires are usually set by the same condition.
+func (t *IfTask) Execute(ctx context.Context) error {
+	logger := ContextLogger(ctx, t.Logger)
+	ok, err := t.predicate(t.params)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate condition of task '%s': %w", t.name, err)
+	}
+	branch := t.otherwise
+	if ok {
+		branch = t.then
+	}
+	if branch == nil {
+		logger.Info("Condition is false and there is no else branch, skipping")
+		return nil
+	}
+	logger.Info("Condition is %t, running branch %s", ok, branch.Name())
+	return runBranch(ctx, t.name, branch)
+}
+
+// SwitchTask runs the task registered for the value returned by its selector, or the default task
+type SwitchTask struct {
+	name        string
+	params      *Parameters
+	selector    Selector
+	cases       map[string]Task
+	defaultTask Task
+	Logger      Logger
+}
+
+// NewSwitchTask creates a task choosing among its cases with the value returned by selector
+func NewSwitchTask(name string, params *Parameters, selector Selector) *SwitchTask {
+	return &SwitchTask{
+		name:     name,
+		params:   params,
+		selector: selector,
+		cases:    make(map[string]Task),
+		Logger:   &DefaultLogger{prefix: fmt.Sprintf("Switch Task - %s", name)},
+	}
+}
+
+// Case registers the task executed when the selector returns value
+func (t *SwitchTask) Case(value string, task Task) *SwitchTask {
+	t.cases[value] = task
+	return t
+}
+
+// Default sets the task executed when no case matches
+func (t *SwitchTask) Default(task Task) *SwitchTask {
+	t.defaultTask = task
+	return t
+}
+
+func (t *SwitchTask) Name() string {
+	return t.name
+}
+
+func (t *SwitchTask) Validate() error {
+	if t.selector == nil {
+		return ErrNoPredicate
+	}
+	if len(t.cases) == 0 && t.defaultTask == nil {
+		return ErrNoBranch
+	}
+	return nil
+}
+
+func (t *SwitchTask) Execute(ctx context.Context) error {
+	logger := ContextLogger(ctx, t.Logger)
+	value, err := t.selector(t.params)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate selector of task '%s': %w", t.name, err)
+	}
+	branch, ok := t.cases[value]
+	if !ok {
+		branch = t.defaultTask
+	}
+	if branch == nil {
+		logger.Info("No case matches %q and there is no default, skipping", value)
+		return nil
+	}
+	if ok {
+		logger.Info("Case %q matched, running branch %s", value, branch.Name())
+	} else {
+		logger.Info("No case matches %q, running default branch %s", value, branch.Name())
+	}
+	return runBranch(ctx, t.name, branch)
+}
+
+func runBranch(ctx context.Context, name string, branch Task) error {
+	return observeTask(ctx, branch, func(ctx context.Context) error {
+		if err := branch.Validate(); err != nil {
+			return fmt.Errorf("validation failed in branch '%s' of task '%s': %w", branch.Name(), name, err)
+		}
+		if err := branch.Execute(ctx); err != nil {
+			return fmt.Errorf("branch '%s' of task '%s' failed: %w", branch.Name(), name, err)
+		}
+		return nil
+	})
+}
+
+// HasValue holds when the key is set to a value that is not empty: nil, "", zero numbers, false
+// and empty slices or maps all count as empty
+func HasValue(key string) Predicate {
+	return func(params *Parameters) (bool, error) {
+		value, ok := params.Lookup(key)
+		if !ok || value == nil {
+			return false, nil
+		}
+		return !reflect.ValueOf(value).IsZero() && !isEmptyCollection(value), nil
+	}
+}
+
+// Not negates the predicate
+func Not(predicate Predicate) Predicate {
+	return func(params *Parameters) (bool, error) {
+		ok, err := predicate(params)
+		return !ok, err
+	}
+}
+
+// TemplatePredicate holds when the Go template renders to a value other than "", "false", "0" or
+// "<no value>", the output of a missing key. Example: `{{eq .status "pending"}}`
+func TemplatePredicate(text string) (Predicate, error) {
+	selector, err := TemplateSelector(text)
+	if err != nil {
+		return nil, err
+	}
+	return func(params *Parameters) (bool, error) {
+		value, err := selector(params)
+		if err != nil {
+			return false, err
+		}
+		switch value {
+		case "", "false", "0", "<no value>":
+			return false, nil
+		}
+		return true, nil
+	}, nil
+}
+
+// TemplateSelector selects the case named by the rendered Go template, trimmed of surrounding spaces.
+// Unlike request templates, missing keys are allowed and render as "<no value>".
+func TemplateSelector(text string) (Selector, error) {
+	tmpl, err := template.New("condition").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition template: %w", err)
+	}
+	return func(params *Parameters) (string, error) {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, params.Snapshot()); err != nil {
+			return "", fmt.Errorf("failed to render condition template: %w", err)
+		}
+		return strings.TrimSpace(sb.String()), nil
+	}, nil
+}
+
+func isEmptyCollection(value any) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrPathNotFound is returned when a required extraction path is missing from the response
+var ErrPathNotFound = errors.New("path not found")
+
+// ErrAmbiguousPath is returned when a path segment matches no field exactly and several fields
+// case-insensitively
+var ErrAmbiguousPath = errors.New("ambiguous path")
+
+// ExtractRule reads a value from the JSON response of an HTTPTask and stores it into the task parameters
+type ExtractRule struct {
+	path       string
+	key        string
+	segments   []string
+	required   bool
+	fallback   any
+	hasDefault bool
+	zero       any
+	decode     func(raw json.RawMessage) (any, error)
+	err        error
+}
+
+// ExtractOption configures an ExtractRule
+type ExtractOption func(*ExtractRule)
+
+// Required makes the extraction fail when the path is missing from the response. The response of a mutating
+// request not sent during a dry run is not the website's, so the rule then stores its default, or the zero
+// value of its type, instead.
+func Required() ExtractOption {
+	return func(r *ExtractRule) {
+		r.required = true
+	}
+}
+
+// Default sets the value stored when the path is missing from the response.
+// The value must have the type the rule decodes into.
+func Default(value any) ExtractOption {
+	return func(r *ExtractRule) {
+		r.fallback = value
+		r.hasDefault = true
+	}
+}
+
+// Extract creates a rule that decodes the value found at path into T and stores it under key.
+// Paths are dotted field names with optional indexes, e.g. "questions", "quiz[0].id" or "quiz[*].id"
+// where [*] collects the remaining path of every array element into a list.
+func Extract[T any](path, key string, opts ...ExtractOption) ExtractRule {
+	var zero T
+	rule := ExtractRule{
+		path: path,
+		key:  key,
+		zero: zero,
+		decode: func(raw json.RawMessage) (any, error) {
+			return decodeRaw[T](raw)
+		},
+	}
+	for _, opt := range opts {
+		opt(&rule)
+	}
+
+	rule.segments, rule.err = splitPath(path)
+	if key == "" {
+		rule.err = errors.Join(rule.err, fmt.Errorf("extract %q: missing target key", path))
+	}
+	if rule.hasDefault {
+		if _, ok := rule.fallback.(T); !ok {
+			rule.err = errors.Join(rule.err, fmt.Errorf("extract %q: default value %T is not a %T", path, rule.fallback, zero))
+		}
+	}
+	return rule
+}
+
+// WithExtractRules sets the rules applied to the response before the post extract function runs
+func WithExtractRules(rules ...ExtractRule) Option {
+	return func(t *HTTPTask) {
+		t.extractRules = append(t.extractRules, rules...)
+	}
+}
+
+// Path returns the path expression read by the rule
+func (r ExtractRule) Path() string {
+	return r.path
+}
+
+// Key returns the parameter key written by the rule
+func (r ExtractRule) Key() string {
+	return r.key
+}
+
+// applyExtractRules runs the rules against the JSON body and stores the results into params. When notSent
+// is set the body did not come from the website and the required paths may be missing.
+func applyExtractRules(body []byte, rules []ExtractRule, params *Parameters, notSent bool) error {
+	for _, rule := range rules {
+		if rule.err != nil {
+			return rule.err
+		}
+		raw, found, err := lookupPath(body, rule.segments)
+		if err != nil {
+			return fmt.Errorf("extract %q: %w", rule.path, err)
+		}
+		if !found {
+			switch {
+			case rule.required && !notSent:
+				return fmt.Errorf("extract %q: %w", rule.path, ErrPathNotFound)
+			case rule.hasDefault:
+				params.Put(rule.key, rule.fallback)
+			case rule.required:
+				params.Put(rule.key, rule.zero)
+			}
+			continue
+		}
+		value, err := rule.decode(raw)
+		if err != nil {
+			return fmt.Errorf("extract %q: %w", rule.path, err)
+		}
+		params.Put(rule.key, value)
+	}
+	return nil
+}
+
+func decodeRaw[T any](raw json.RawMessage) (T, error) {
+	var value T
+	err := json.Unmarshal(raw, &value)
+	if err == nil {
+		return value, nil
+	}
+	// Numbers and booleans are accepted where strings are expected, e.g. numeric IDs extracted as []string.
+	if retry, ok := stringifyScalars(raw); ok && json.Unmarshal(retry, &value) == nil {
+		return value, nil
+	}
+	return value, fmt.Errorf("failed to decode into %T: %w", value, err)
+}
+
+// stringifyScalars rewrites every number and boolean of the document as a JSON string
+func stringifyScalars(raw json.RawMessage) (json.RawMessage, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, false
+	}
+	var convert func(node any) any
+	convert = func(node any) any {
+		switch v := node.(type) {
+		case json.Number:
+			return v.String()
+		case bool:
+			return strconv.FormatBool(v)
+		case []any:
+			for i := range v {
+				v[i] = convert(v[i])
+			}
+		case map[string]any:
+			for key := range v {
+				v[key] = convert(v[key])
+			}
+		}
+		return node
+	}
+	converted, err := json.Marshal(convert(document))
+	return converted, err == nil
+}
+
+// splitPath turns an expression like "quiz[*].id" into the segments "quiz", "*", "id"
+func splitPath(path string) ([]string, error) {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(part, "[")
+		if name != "" {
+			segments = append(segments, name)
+		}
+		for rest != "" {
+			index, tail, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid path %q: unclosed index", path)
+			}
+			segments = append(segments, index)
+			rest = strings.TrimPrefix(tail, "[")
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	return segments, nil
+}
+
+// lookupPath walks the raw JSON document following the segments. Object fields are matched
+// exactly first and case-insensitively after, failing with ErrAmbiguousPath when several fields
+// only differ by case.
+func lookupPath(raw json.RawMessage, segments []string) (json.RawMessage, bool, error) {
+	for i, segment := range segments {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			return nil, false, nil
+		}
+		switch raw[0] {
+		case '{':
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &object); err != nil {
+				return nil, false, nil
+			}
+			child, ok := object[segment]
+			if !ok {
+				var matches []string
+				for key, value := range object {
+					if strings.EqualFold(key, segment) {
+						matches = append(matches, key)
+						child, ok = value, true
+					}
+				}
+				if len(matches) > 1 {
+					slices.Sort(matches)
+					return nil, false, fmt.Errorf("%w: %q matches the fields %s", ErrAmbiguousPath, segment, strings.Join(matches, ", "))
+				}
+			}
+			if !ok {
+				return nil, false, nil
+			}
+			raw = child
+		case '[':
+			var array []json.RawMessage
+			if err := json.Unmarshal(raw, &array); err != nil {
+				return nil, false, nil
+			}
+			if segment == "*" {
+				items := make([]json.RawMessage, 0, len(array))
+				for _, item := range array {
+					child, ok, err := lookupPath(item, segments[i+1:])
+					if err != nil {
+						return nil, false, err
+					}
+					if ok {
+						items = append(items, child)
+					}
+				}
+				collected, err := json.Marshal(items)
+				if err != nil {
+					return nil, false, nil
+				}
+				return collected, true, nil
+			}
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(array) {
+				return nil, false, nil
+			}
+			raw = array[index]
+		default:
+			return nil, false, nil
+		}
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, false, nil
+	}
+	return raw, true, nil
+}
+
+// readAndRestoreBody reads the response body and replaces it so post extract functions can still decode it
+func readAndRestoreBody(body io.ReadCloser) ([]byte, io.ReadCloser, error) {
+	data, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, io.NopCloser(bytes.NewReader(data)), nil
+}
@@ -1,17 +1,24 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
 )
 
-type PreRequestFunc func() error
+// HTTPError is returned by an HTTPTask when the response status is not expected, inspect it with errors.As
+type HTTPError = httprequest.HTTPError
 
-type PostExtractFunc func(resp *http.Response, task *HTTPTask) error
+type PreRequestFunc func(ctx context.Context) error
+
+type PostExtractFunc func(ctx context.Context, resp *http.Response, task *HTTPTask) error
 
 // HTTPTask represents a task that makes an HTTP request
 type HTTPTask struct {
@@ -19,12 +26,19 @@ type HTTPTask struct {
 	URL            string
 	method         httprequest.HTTPMethod
 	Headers        httprequest.Headers
-	Params         Parameters
+	Params         *Parameters
 	requiredParams []string
+	requiredKeys   []ParamKey
 	RequestBody    []byte
-	waitTime       time.Duration
+	query          url.Values
 	Logger         Logger
 
+	templates       requestTemplates
+	bodyEncoder     BodyEncoder
+	extractRules    []ExtractRule
+	retryPolicy     *httprequest.RetryPolicy
+	client          *httprequest.Client
+	expectedStatus  []int
 	preRequestFunc  PreRequestFunc
 	postExtractFunc PostExtractFunc
 }
@@ -38,23 +52,35 @@ func (t *HTTPTask) Validate() error {
 	if t.Headers == nil {
 		return errors.New("missing headers")
 	}
+	if t.templates.err != nil {
+		return fmt.Errorf("task %q: %w", t.name, t.templates.err)
+	}
+	for _, rule := range t.extractRules {
+		if rule.err != nil {
+			return fmt.Errorf("task %q: %w", t.name, rule.err)
+		}
+	}
 
 	for _, param := range t.requiredParams {
-		if _, exists := t.Params[param]; !exists {
+		if !t.Params.Has(param) {
 			return fmt.Errorf("task %q: missing required parameter %q", t.name, param)
 		}
 	}
+	for _, key := range t.requiredKeys {
+		if err := key.Check(t.Params); err != nil {
+			return fmt.Errorf("task %q: invalid required parameter: %w", t.name, err)
+		}
+	}
 	return nil
 }
 
 // NewHTTPTask creates a new HTTP task
-func NewHTTPTask(name string, method httprequest.HTTPMethod, headers httprequest.Headers, params Parameters, options ...Option) *HTTPTask {
+func NewHTTPTask(name string, method httprequest.HTTPMethod, headers httprequest.Headers, params *Parameters, options ...Option) *HTTPTask {
 	task := &HTTPTask{
 		name:           name,
 		method:         method,
 		Headers:        headers,
 		Params:         params,
-		waitTime:       time.Second * 2,
 		Logger:         &DefaultLogger{prefix: fmt.Sprintf("HTTP Task - %s", name)},
 		requiredParams: []string{},
 	}
@@ -62,7 +88,7 @@ func NewHTTPTask(name string, method httprequest.HTTPMethod, headers httprequest
 		task.Headers = make(httprequest.Headers)
 	}
 	if task.Params == nil {
-		task.Params = make(Parameters)
+		task.Params = NewParameters(nil)
 	}
 
 	for _, option := range options {
@@ -81,6 +107,13 @@ func WithRequiredParams(params []string) Option {
 	}
 }
 
+// WithRequiredKeys sets typed parameter keys that must hold a value of the expected type
+func WithRequiredKeys(keys ...ParamKey) Option {
+	return func(t *HTTPTask) {
+		t.requiredKeys = append([]ParamKey(nil), keys...)
+	}
+}
+
 // WithPreRequestFunc sets a custom function for pre-request
 func WithPreRequestFunc(fn PreRequestFunc) Option {
 	return func(t *HTTPTask) {
@@ -95,48 +128,160 @@ func WithPostExtractFunc(fn PostExtractFunc) Option {
 	}
 }
 
-// Execute performs the HTTP request
-func (t *HTTPTask) Execute() error {
-	t.Logger.Info("Initiating task %s...", t.name)
+// WithRetryPolicy sets the retry policy of the task, overriding the http_request default policy
+func WithRetryPolicy(policy httprequest.RetryPolicy) Option {
+	return func(t *HTTPTask) {
+		t.retryPolicy = &policy
+	}
+}
+
+// WithClient sends the requests of the task with the client, overriding the client of the rpa
+func WithClient(client *httprequest.Client) Option {
+	return func(t *HTTPTask) {
+		t.client = client
+	}
+}
+
+// WithExpectedStatus sets the status codes accepted by the task. Without it any 2xx status is accepted.
+// Other statuses fail the task with an *HTTPError.
+func WithExpectedStatus(codes ...int) Option {
+	return func(t *HTTPTask) {
+		t.expectedStatus = append([]int(nil), codes...)
+	}
+}
+
+// Execute performs the HTTP request. Cancelling the context aborts the in-flight request and its wait for the
+// rate limit of the client.
+// Request templates are rendered after the pre request function, so it can prepare the parameters they use.
+func (t *HTTPTask) Execute(ctx context.Context) error {
+	logger := ContextLogger(ctx, t.Logger)
+	logger.Info("Initiating task %s...", t.name)
 	var resp *http.Response
 	var err error
 
 	if t.preRequestFunc != nil {
-		t.Logger.Info("Executing pre request function...")
-		if err := t.preRequestFunc(); err != nil {
+		logger.Info("Executing pre request function...")
+		if err := t.preRequestFunc(ctx); err != nil {
 			return fmt.Errorf("pre request failed: %w", err)
 		}
 	}
+	headers, err := t.templates.apply(t)
+	if err != nil {
+		return fmt.Errorf("task %q: %w", t.name, err)
+	}
 
-	if t.method == httprequest.GET {
-		t.Logger.Info("Executing GET request...")
-		resp, err = httprequest.DoGet(t.URL, t.Headers)
-	} else if t.method == httprequest.POST {
-		t.Logger.Info("Executing POST request...")
-		resp, err = httprequest.DoPost(t.URL, t.Headers, t.RequestBody)
-	} else {
+	if !t.method.Valid() {
 		return fmt.Errorf("task %q: unsupported HTTP method %q", t.name, t.method)
 	}
+	if t.bodyEncoder != nil && t.method.HasBody() {
+		body, err := t.bodyEncoder(t.Params)
+		if err != nil {
+			return fmt.Errorf("task %q: %w", t.name, err)
+		}
+		t.RequestBody = body.Data
+		maps.DeleteFunc(headers, func(key, _ string) bool { return strings.EqualFold(key, "Content-Type") })
+		headers["Content-Type"] = body.ContentType
+	}
+	requestURL, err := httprequest.AddQuery(t.URL, t.query)
+	if err != nil {
+		return fmt.Errorf("task %q: %w", t.name, err)
+	}
+	logger.Info("Executing %s request to %s", t.method, requestURL)
+	ls := listenersFrom(ctx)
+	request := RequestEvent{
+		Rpa:     rpaName(ctx),
+		Task:    t.name,
+		Step:    StepPath(ctx),
+		Method:  t.method,
+		URL:     requestURL,
+		Headers: headers,
+		Body:    t.requestBody(),
+	}
+	ls.OnRequest(ctx, request)
+	attempts := 1
+	start := time.Now()
+	resp, err = t.httpClient(ctx).Do(ctx, t.method, requestURL, headers, request.Body, t.effectiveRetryPolicy(ctx, &attempts))
 	defer func() {
 		if resp != nil && resp.Body != nil {
 			_ = resp.Body.Close()
 		}
 	}()
+	response := ResponseEvent{Request: request, Attempts: attempts, Duration: time.Since(start), Err: err}
+	if resp != nil {
+		response.StatusCode = resp.StatusCode
+	}
+	ls.OnResponse(ctx, response)
 
 	if err != nil {
 		return fmt.Errorf("HTTP request failed: %w", err)
 	}
+	if err := httprequest.CheckStatus(resp, t.expectedStatus...); err != nil {
+		logger.Error("Unexpected response: %v", err)
+		return fmt.Errorf("task %q: %w", t.name, err)
+	}
+
+	if len(t.extractRules) > 0 {
+		logger.Info("Applying %d extract rules...", len(t.extractRules))
+		var body []byte
+		if body, resp.Body, err = readAndRestoreBody(resp.Body); err != nil {
+			return fmt.Errorf("task %q: failed to read response: %w", t.name, err)
+		}
+		notSent := httprequest.IsDryRun(ctx) && httprequest.IsMutating(t.method)
+		if err := applyExtractRules(body, t.extractRules, t.Params, notSent); err != nil {
+			return fmt.Errorf("task %q: extraction failed: %w", t.name, err)
+		}
+	}
 
 	if t.postExtractFunc != nil {
-		t.Logger.Info("Executing post extract function...")
-		if err := t.postExtractFunc(resp, t); err != nil {
+		logger.Info("Executing post extract function...")
+		if err := t.postExtractFunc(ctx, resp, t); err != nil {
 			return fmt.Errorf("post extraction failed: %w", err)
 		}
 	}
 
-	if t.waitTime > 0 {
-		time.Sleep(t.waitTime)
-	}
-	t.Logger.Info("HTTP Task executed successfully")
+	logger.Info("HTTP Task executed successfully")
 	return nil
 }
+
+// httpClient returns the client of the task, or the client of the rpa
+func (t *HTTPTask) httpClient(ctx context.Context) *httprequest.Client {
+	if t.client != nil {
+		return t.client
+	}
+	return clientFrom(ctx)
+}
+
+// requestBody returns the body sent with the request, GET and HEAD requests never carry one
+func (t *HTTPTask) requestBody() []byte {
+	if !t.method.HasBody() {
+		return nil
+	}
+	return t.RequestBody
+}
+
+// effectiveRetryPolicy returns the task policy, or the default one, logging every retry through the task
+// logger, reporting it to the listeners of the run and counting it in attempts
+func (t *HTTPTask) effectiveRetryPolicy(ctx context.Context, attempts *int) httprequest.RetryPolicy {
+	logger := ContextLogger(ctx, t.Logger)
+	policy := httprequest.GetDefaultRetryPolicy()
+	if t.retryPolicy != nil {
+		policy = *t.retryPolicy
+	}
+	notify := policy.OnRetry
+	policy.OnRetry = func(attempt int, delay time.Duration, reason error) {
+		logger.Warn("Attempt %d/%d failed: %v - retrying in %s", attempt, policy.MaxAttempts, reason, delay.Round(time.Millisecond))
+		*attempts = attempt + 1
+		listenersFrom(ctx).OnRetry(ctx, RetryEvent{
+			Rpa:     rpaName(ctx),
+			Task:    t.name,
+			Step:    StepPath(ctx),
+			Attempt: attempt,
+			Delay:   delay,
+			Err:     reason,
+		})
+		if notify != nil {
+			notify(attempt, delay, reason)
+		}
+	}
+	return policy
+}
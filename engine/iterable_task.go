@@ -1,70 +1,421 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // CurrentElement represents a key or identifier for the current element in a collection or operation.
 // CurrentIndex represents a key or identifier for the current index in a collection or operation.
+// FailedElements is the suffix of the key listing the elements that failed, "<elementsKey>_failedElements".
 const (
 	CurrentElement = "currentElement"
 	CurrentIndex   = "currentIndex"
+	FailedElements = "failedElements"
 )
 
+// ErrTooManyFailures is returned when an iteration stops after reaching its maximum number of failures
+var ErrTooManyFailures = errors.New("too many failed elements")
+
+// ElementError is the error of a single failed element. The error returned by a failed iteration, like
+// IterableTask.Failures, joins one ElementError per failed element.
+type ElementError struct {
+	Index   int
+	Element any
+	Err     error
+}
+
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("element %v with index %d failed: %v", e.Element, e.Index, e.Err)
+}
+
+func (e *ElementError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorMode defines how an IterableTask reacts when the task of an element fails.
+type ErrorMode int
+
+const (
+	// FailFast stops the iteration on the first failing element and returns its error.
+	FailFast ErrorMode = iota
+	// CollectAll skips failing elements and continues with the next ones. The iteration succeeds once every
+	// element was processed; the failed elements are stored under "<elementsKey>_failedElements" and their
+	// errors returned by IterableTask.Failures.
+	CollectAll
+)
+
+// TaskFactory builds a new task instance bound to the given parameters.
+// It lets an IterableTask run each element against its own isolated parameter scope.
+type TaskFactory func(params *Parameters) Task
+
+// IterableOption configures optional behaviour of an IterableTask.
+type IterableOption func(*iterableConfig)
+
+type iterableConfig struct {
+	concurrency int
+	factory     TaskFactory
+	errorMode   ErrorMode
+	outputKey   string
+	resultsKey  string
+	maxFailures int
+	logField    string
+}
+
+// WithConcurrency processes up to limit elements at the same time. Each element gets its own child
+// scope of the parameters and a task instance built by factory, so elements never share state. A limit of 1 or
+// less keeps the sequential behaviour.
+func WithConcurrency(limit int, factory TaskFactory) IterableOption {
+	return func(c *iterableConfig) {
+		c.concurrency = limit
+		c.factory = factory
+	}
+}
+
+// WithErrorMode sets how failing elements are handled. The default is FailFast.
+func WithErrorMode(mode ErrorMode) IterableOption {
+	return func(c *iterableConfig) {
+		c.errorMode = mode
+	}
+}
+
+// WithMaxFailures continues past failing elements like CollectAll, but stops the iteration once limit
+// elements failed, returning their errors joined with ErrTooManyFailures. A limit of 0 never stops the
+// iteration, like CollectAll, and a negative one fails the validation.
+func WithMaxFailures(limit int) IterableOption {
+	return func(c *iterableConfig) {
+		c.errorMode = CollectAll
+		c.maxFailures = limit
+	}
+}
+
+// WithLogField logs the element being processed under the given field name, such as "quiz_id", in
+// every record of the element's tasks, besides the "element_index" field always added
+func WithLogField(name string) IterableOption {
+	return func(c *iterableConfig) {
+		c.logField = name
+	}
+}
+
+// WithResults collects the value each element stored under outputKey and puts the ordered list under
+// resultsKey once the iteration finishes. Elements that did not set outputKey contribute nil.
+func WithResults(outputKey, resultsKey string) IterableOption {
+	return func(c *iterableConfig) {
+		c.outputKey = outputKey
+		c.resultsKey = resultsKey
+	}
+}
+
 // IterableTask represents a task that iterates over a collection of elements performing operations.
 // It is a generic type parameterized by T, supporting various data types.
 // The task leverages an inner Task implementation, using provided parameters to iterate.
 // Elements are identified using a key and are fetched dynamically from the given parameters.
-// Current iteration context, such as element and index, is stored in parameters for each iteration.
+// Current iteration context, such as element and index, is stored in a child scope of the parameters
+// that is discarded once the element is processed.
+// Once the iteration ends, the elements that failed are stored as []T under "<elementsKey>_failedElements",
+// so a follow-up task can retry them.
 type IterableTask[T any] struct {
 	task        Task
 	elementsKey string
-	params      Parameters
+	params      *Parameters
 	name        string
 	Logger      Logger
+	config      iterableConfig
+	failures    []*ElementError
 }
 
 // NewIterableTask creates a new instance of IterableTask with a specified name, elementsKey, underlying task, and parameters.
 // It enables iteration over elements of type T, processing each using the provided task.
-func NewIterableTask[T any](name string, elementsKey string, task Task, params Parameters) *IterableTask[T] {
-	return &IterableTask[T]{
-		task,
-		elementsKey,
-		params,
-		name,
-		&DefaultLogger{prefix: fmt.Sprintf("Iterable Task - %s", name)},
+func NewIterableTask[T any](name string, elementsKey string, task Task, params *Parameters, opts ...IterableOption) *IterableTask[T] {
+	iterable := &IterableTask[T]{
+		task:        task,
+		elementsKey: elementsKey,
+		params:      params,
+		name:        name,
+		Logger:      &DefaultLogger{prefix: fmt.Sprintf("Iterable Task - %s", name)},
+	}
+	for _, opt := range opts {
+		opt(&iterable.config)
 	}
+	return iterable
 }
 
-// Execute iterates over a list of elements, setting the current element and index, and executes the wrapped task for each item. Returns an error if the task execution fails.
-func (t *IterableTask[T]) Execute() error {
+// Execute iterates over a list of elements, setting the current element and index, and executes the wrapped task for each item. Returns an error if the task execution fails or the context is cancelled between elements.
+func (t *IterableTask[T]) Execute(ctx context.Context) error {
+	logger := ContextLogger(ctx, t.Logger)
 	rawElements := t.params.Get(t.elementsKey)
-	elements, ok := rawElements.([]T)
+	elements, ok := toElements[T](rawElements)
 	if !ok {
-		return fmt.Errorf("expected '%s' to be of type []T, but it was %T", t.elementsKey, rawElements)
+		var zero T
+		return fmt.Errorf("expected '%s' to be of type []%T, but it was %T", t.elementsKey, zero, rawElements)
+	}
+
+	var err error
+	if t.config.concurrency > 1 {
+		logger.Info("Initiating iterable task with %d workers", t.config.concurrency)
+		err = t.executeConcurrently(ctx, elements)
+	} else {
+		logger.Info("Initiating iterable task")
+		err = t.executeSequentially(ctx, elements)
+	}
+	if err != nil {
+		return err
+	}
+	if len(t.failures) > 0 {
+		logger.Warn("Finished iterable task, %d of %d elements failed", len(t.failures), len(elements))
+		return nil
+	}
+	logger.Info("Finished iterable task")
+	return nil
+}
+
+// Failures returns the errors of the elements that failed during the last execution joined in iteration
+// order, or nil when none failed
+func (t *IterableTask[T]) Failures() error {
+	errs := make([]error, len(t.failures))
+	for i, failure := range t.failures {
+		errs[i] = failure
 	}
-	t.Logger.Info("Initiating iterable task")
+	return errors.Join(errs...)
+}
+
+func (t *IterableTask[T]) executeSequentially(ctx context.Context, elements []T) error {
+	logger := ContextLogger(ctx, t.Logger)
+	results := make([]any, len(elements))
+	failures := &failureLog[T]{}
+	defer func() { t.storeFailures(failures) }()
 	for index, element := range elements {
-		t.params.Put(t.elementsKey+"_"+CurrentElement, element)
-		t.params.Put(t.elementsKey+"_"+CurrentIndex, index)
-		t.Logger.Info("Executing task for element %s with index %d", element, index)
-		if err := t.task.Execute(); err != nil {
-			return fmt.Errorf("HTTP request failed in iterable task: %w", err)
+		if err := ctx.Err(); err != nil {
+			logger.Warn("Iteration interrupted at index %d of %d", index, len(elements))
+			return failures.join(fmt.Errorf("iterable task '%s' interrupted at index %d: %w", t.name, index, err))
+		}
+		t.params.EnterScope()
+		setCurrent(t.params, t.elementsKey, element, index)
+		err := t.runElement(ctx, t.task, element, index)
+		if t.config.outputKey != "" {
+			results[index] = t.params.Get(t.config.outputKey)
+		}
+		t.params.ExitScope()
+		if err != nil {
+			failed := failures.add(element, index, err)
+			if t.config.errorMode == FailFast {
+				return failures.join()
+			}
+			logger.Error("Element %v with index %d failed: %v", element, index, err)
+			if t.reachedMaxFailures(failed) {
+				logger.Warn("Stopping after %d failed elements", failed)
+				return failures.join(fmt.Errorf("iterable task '%s': %w (%d)", t.name, ErrTooManyFailures, failed))
+			}
 		}
 	}
-	t.Logger.Info("Finished iterable task")
+	t.storeResults(results)
 	return nil
 }
 
+// executeConcurrently runs the elements through a bounded worker pool. In FailFast mode the first
+// failure, and with WithMaxFailures the last allowed one, cancels the elements still running and
+// prevents new ones from starting.
+func (t *IterableTask[T]) executeConcurrently(ctx context.Context, elements []T) error {
+	logger := ContextLogger(ctx, t.Logger)
+	if t.config.factory == nil {
+		return fmt.Errorf("iterable task '%s': concurrency requires a task factory", t.name)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]any, len(elements))
+	failures := &failureLog[T]{}
+	defer func() { t.storeFailures(failures) }()
+	slots := make(chan struct{}, t.config.concurrency)
+	var wg sync.WaitGroup
+	var stopErr error
+	var stopping atomic.Bool
+
+	for index, element := range elements {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			logger.Warn("Iteration interrupted at index %d of %d", index, len(elements))
+			stopErr = fmt.Errorf("iterable task '%s' interrupted at index %d: %w", t.name, index, ctx.Err())
+			break
+		}
+
+		scope := t.params.NewScope()
+		setCurrent(scope, t.elementsKey, element, index)
+		task := t.config.factory(scope)
+
+		wg.Add(1)
+		go func(index int, element T) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := t.runElement(ctx, task, element, index); err != nil {
+				if stopping.Load() && errors.Is(err, context.Canceled) {
+					// cancelled because the iteration stopped after other failures
+					return
+				}
+				failed := failures.add(element, index, err)
+				logger.Error("Element %v with index %d failed: %v", element, index, err)
+				if t.config.errorMode == FailFast || t.reachedMaxFailures(failed) {
+					stopping.Store(true)
+					cancel()
+				}
+			}
+			if t.config.outputKey != "" {
+				results[index] = scope.Get(t.config.outputKey)
+			}
+		}(index, element)
+	}
+	wg.Wait()
+
+	failed := failures.len()
+	switch {
+	case t.config.errorMode == FailFast && failed > 0:
+		return failures.join()
+	case t.reachedMaxFailures(failed):
+		logger.Warn("Stopped after %d failed elements", failed)
+		return failures.join(fmt.Errorf("iterable task '%s': %w (%d)", t.name, ErrTooManyFailures, failed))
+	case stopErr != nil:
+		return failures.join(stopErr)
+	}
+	t.storeResults(results)
+	return nil
+}
+
+func (t *IterableTask[T]) reachedMaxFailures(failed int) bool {
+	return t.config.maxFailures > 0 && failed >= t.config.maxFailures
+}
+
+// storeFailures puts the failed elements, in iteration order, under "<elementsKey>_failedElements" and
+// keeps their errors for Failures
+func (t *IterableTask[T]) storeFailures(failures *failureLog[T]) {
+	t.failures = failures.sorted()
+	t.params.Put(t.elementsKey+"_"+FailedElements, failures.elements())
+}
+
+// failureLog records the failed elements of an iteration, safe for use by concurrent workers
+type failureLog[T any] struct {
+	mu     sync.Mutex
+	errors []*ElementError
+}
+
+// add records the failure and returns how many elements failed so far
+func (f *failureLog[T]) add(element T, index int, err error) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, &ElementError{Index: index, Element: element, Err: err})
+	return len(f.errors)
+}
+
+func (f *failureLog[T]) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.errors)
+}
+
+func (f *failureLog[T]) sorted() []*ElementError {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sort.Slice(f.errors, func(i, j int) bool { return f.errors[i].Index < f.errors[j].Index })
+	return f.errors
+}
+
+func (f *failureLog[T]) elements() []T {
+	failed := make([]T, 0)
+	for _, failure := range f.sorted() {
+		failed = append(failed, failure.Element.(T))
+	}
+	return failed
+}
+
+// join combines the element errors, in iteration order, with the extra errors
+func (f *failureLog[T]) join(extra ...error) error {
+	var errs []error
+	for _, failure := range f.sorted() {
+		errs = append(errs, failure)
+	}
+	return errors.Join(append(errs, extra...)...)
+}
+
+// setCurrent stores the element and index of the iteration in its scope, both under the plain
+// CurrentElement and CurrentIndex keys, shadowing any outer iteration, and prefixed by the elements key.
+func setCurrent(scope *Parameters, elementsKey string, element any, index int) {
+	scope.Put(CurrentElement, element)
+	scope.Put(CurrentIndex, index)
+	scope.Put(elementsKey+"_"+CurrentElement, element)
+	scope.Put(elementsKey+"_"+CurrentIndex, index)
+}
+
+// runElement validates and executes the task of one element, notifying the listeners of the run.
+// Elements recorded as completed in the run journal are skipped, and the ones that succeed are recorded.
+func (t *IterableTask[T]) runElement(ctx context.Context, task Task, element T, index int) error {
+	ctx = withElementStep(ctx, t.name, element, index)
+	ctx = WithLogFields(ctx, "element_index", index)
+	if t.config.logField != "" {
+		ctx = WithLogFields(ctx, t.config.logField, element)
+	}
+	logger := ContextLogger(ctx, t.Logger)
+	step := StepPath(ctx)
+	ls := listenersFrom(ctx)
+	event := ElementEvent{Rpa: rpaName(ctx), Task: t.name, Step: step, Index: index, Element: element}
+	journal := journalFrom(ctx)
+	if journal != nil && journal.IsCompleted(step) {
+		logger.Info("Skipping element %v with index %d, completed in a previous run", element, index)
+		event.Skipped = true
+		ls.AfterElement(ctx, event)
+		return nil
+	}
+
+	logger.Info("Executing task for element %v with index %d", element, index)
+	ls.BeforeElement(ctx, event)
+	start := time.Now()
+	err := observeTask(ctx, task, func(ctx context.Context) error {
+		if err := task.Validate(); err != nil {
+			return err
+		}
+		return task.Execute(ctx)
+	})
+	if err == nil && journal != nil {
+		err = journal.MarkCompleted(step)
+	}
+	event.Duration = time.Since(start)
+	event.Err = err
+	ls.AfterElement(ctx, event)
+	return err
+}
+
+func (t *IterableTask[T]) storeResults(results []any) {
+	if t.config.resultsKey != "" {
+		t.params.Put(t.config.resultsKey, results)
+	}
+}
+
 // Validate checks if the IterableTask has a valid elementsKey and ensures the required parameter exists; returns an error if validation fails.
 func (t *IterableTask[T]) Validate() error {
 	if t.elementsKey == "" {
 		return errors.New("missing element key")
 	}
-	if t.params.Get(t.elementsKey) == nil {
+	rawElements := t.params.Get(t.elementsKey)
+	if rawElements == nil {
 		return errors.New("missing required parameter: " + t.elementsKey)
 	}
+	if _, ok := toElements[T](rawElements); !ok {
+		var zero T
+		return fmt.Errorf("%w: %q is %T, expected []%T", ErrParamType, t.elementsKey, rawElements, zero)
+	}
+	if t.config.concurrency > 1 && t.config.factory == nil {
+		return errors.New("concurrency requires a task factory")
+	}
+	if t.config.maxFailures < 0 {
+		return fmt.Errorf("max failures cannot be negative: %d", t.config.maxFailures)
+	}
 	return nil
 }
 
@@ -72,3 +423,24 @@ func (t *IterableTask[T]) Validate() error {
 func (t *IterableTask[T]) Name() string {
 	return t.name
 }
+
+// toElements converts the raw parameter into []T. Besides []T itself, any slice whose elements
+// are assignable to T is accepted, such as []string or []int iterated by an IterableTask[any].
+func toElements[T any](raw any) ([]T, bool) {
+	if elements, ok := raw.([]T); ok {
+		return elements, true
+	}
+	value := reflect.ValueOf(raw)
+	if value.Kind() != reflect.Slice {
+		return nil, false
+	}
+	elements := make([]T, value.Len())
+	for i := range elements {
+		element, ok := value.Index(i).Interface().(T)
+		if !ok {
+			return nil, false
+		}
+		elements[i] = element
+	}
+	return elements, true
+}
@@ -0,0 +1,290 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+)
+
+var errElement = errors.New("element failed")
+
+// elementTask fails for the elements of fail and records the elements it ran
+type elementTask struct {
+	params *engine.Parameters
+	fail   []int
+	ran    *ranElements
+}
+
+func (t *elementTask) Execute(ctx context.Context) error {
+	element := t.params.Get(engine.CurrentElement).(int)
+	t.ran.add(element)
+	if slices.Contains(t.fail, element) {
+		return errElement
+	}
+	return nil
+}
+
+func (t *elementTask) Validate() error { return nil }
+
+func (t *elementTask) Name() string { return "element" }
+
+type ranElements struct {
+	mu       sync.Mutex
+	elements []int
+}
+
+func (r *ranElements) add(element int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.elements = append(r.elements, element)
+}
+
+func (r *ranElements) sorted() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sorted := slices.Clone(r.elements)
+	slices.Sort(sorted)
+	return sorted
+}
+
+// newIteration iterates over the elements 1 to 6 with the given concurrency
+func newIteration(concurrency int, fail []int, opts ...engine.IterableOption) (*engine.IterableTask[int], *engine.Parameters, *ranElements) {
+	params := engine.NewParameters(map[string]any{"ids": []int{1, 2, 3, 4, 5, 6}})
+	ran := &ranElements{}
+	factory := func(scope *engine.Parameters) engine.Task {
+		return &elementTask{params: scope, fail: fail, ran: ran}
+	}
+	opts = append(opts, engine.WithConcurrency(concurrency, factory))
+	task := engine.NewIterableTask[int]("iterate", "ids", factory(params), params, opts...)
+	return task, params, ran
+}
+
+func failedElements(t *testing.T, params *engine.Parameters) []int {
+	t.Helper()
+	failed, ok := params.Get("ids_" + engine.FailedElements).([]int)
+	if !ok {
+		t.Fatalf("ids_failedElements is %T", params.Get("ids_"+engine.FailedElements))
+	}
+	return failed
+}
+
+func TestIterableTaskErrorModes(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []engine.IterableOption
+		fail         []int
+		wantErr      error
+		wantFailed   []int
+		wantRan      []int
+		wantFailures int
+	}{
+		{
+			name:       "no failure",
+			wantRan:    []int{1, 2, 3, 4, 5, 6},
+			wantFailed: []int{},
+		},
+		{
+			name:       "fail fast stops on the first failure",
+			fail:       []int{2, 4},
+			wantErr:    errElement,
+			wantFailed: []int{2},
+			wantRan:    []int{1, 2},
+		},
+		{
+			name:         "collect all succeeds with the failures recorded",
+			opts:         []engine.IterableOption{engine.WithErrorMode(engine.CollectAll)},
+			fail:         []int{2, 4},
+			wantFailed:   []int{2, 4},
+			wantRan:      []int{1, 2, 3, 4, 5, 6},
+			wantFailures: 2,
+		},
+		{
+			name:       "max failures stops once reached",
+			opts:       []engine.IterableOption{engine.WithMaxFailures(2)},
+			fail:       []int{2, 3, 4},
+			wantErr:    engine.ErrTooManyFailures,
+			wantFailed: []int{2, 3},
+			wantRan:    []int{1, 2, 3},
+		},
+		{
+			name:         "max failures not reached",
+			opts:         []engine.IterableOption{engine.WithMaxFailures(3)},
+			fail:         []int{2, 4},
+			wantFailed:   []int{2, 4},
+			wantRan:      []int{1, 2, 3, 4, 5, 6},
+			wantFailures: 2,
+		},
+		{
+			name:         "zero max failures never stops",
+			opts:         []engine.IterableOption{engine.WithMaxFailures(0)},
+			fail:         []int{1, 2, 3, 4, 5, 6},
+			wantFailed:   []int{1, 2, 3, 4, 5, 6},
+			wantRan:      []int{1, 2, 3, 4, 5, 6},
+			wantFailures: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, params, ran := newIteration(1, tt.fail, tt.opts...)
+			if err := task.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			err := task.Execute(context.Background())
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := failedElements(t, params); !slices.Equal(got, tt.wantFailed) {
+				t.Errorf("failed elements = %v, want %v", got, tt.wantFailed)
+			}
+			if got := ran.sorted(); !slices.Equal(got, tt.wantRan) {
+				t.Errorf("ran elements = %v, want %v", got, tt.wantRan)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			failures := task.Failures()
+			var joined interface{ Unwrap() []error }
+			switch {
+			case tt.wantFailures == 0 && failures != nil:
+				t.Errorf("Failures() = %v, want nil", failures)
+			case tt.wantFailures > 0 && (!errors.As(failures, &joined) || len(joined.Unwrap()) != tt.wantFailures):
+				t.Errorf("Failures() = %v, want %d element errors", failures, tt.wantFailures)
+			}
+		})
+	}
+}
+
+func TestIterableTaskConcurrentErrorModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []engine.IterableOption
+		fail    []int
+		wantErr error
+		// the failed elements are all among wantFailed, at least minFailed of them
+		wantFailed []int
+		minFailed  int
+	}{
+		{
+			name:       "fail fast",
+			fail:       []int{2},
+			wantErr:    errElement,
+			wantFailed: []int{2},
+			minFailed:  1,
+		},
+		{
+			name:       "collect all",
+			opts:       []engine.IterableOption{engine.WithErrorMode(engine.CollectAll)},
+			fail:       []int{2, 5},
+			wantFailed: []int{2, 5},
+			minFailed:  2,
+		},
+		{
+			name:       "max failures",
+			opts:       []engine.IterableOption{engine.WithMaxFailures(2)},
+			fail:       []int{1, 2, 3, 4, 5, 6},
+			wantErr:    engine.ErrTooManyFailures,
+			wantFailed: []int{1, 2, 3, 4, 5, 6},
+			minFailed:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, params, _ := newIteration(3, tt.fail, tt.opts...)
+			err := task.Execute(context.Background())
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			failed := failedElements(t, params)
+			if len(failed) < tt.minFailed || !slices.IsSorted(failed) {
+				t.Errorf("failed elements = %v, want at least %d in order", failed, tt.minFailed)
+			}
+			for _, element := range failed {
+				if !slices.Contains(tt.wantFailed, element) {
+					t.Errorf("element %d recorded as failed", element)
+				}
+			}
+		})
+	}
+}
+
+// blockingTask fails the first element and waits for the cancellation on the others
+type blockingTask struct {
+	params  *engine.Parameters
+	started chan<- int
+}
+
+func (t *blockingTask) Execute(ctx context.Context) error {
+	element := t.params.Get(engine.CurrentElement).(int)
+	t.started <- element
+	if element == 1 {
+		return errElement
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		return errors.New("not cancelled")
+	}
+}
+
+func (t *blockingTask) Validate() error { return nil }
+
+func (t *blockingTask) Name() string { return "blocking" }
+
+func TestIterableTaskConcurrentFailFastCancelsRunningElements(t *testing.T) {
+	params := engine.NewParameters(map[string]any{"ids": []int{1, 2, 3, 4, 5, 6}})
+	started := make(chan int, 6)
+	factory := func(scope *engine.Parameters) engine.Task {
+		return &blockingTask{params: scope, started: started}
+	}
+	task := engine.NewIterableTask[int]("iterate", "ids", factory(params), params, engine.WithConcurrency(2, factory))
+
+	err := task.Execute(context.Background())
+	if !errors.Is(err, errElement) {
+		t.Fatalf("Execute() error = %v, want the error of the first element", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("the elements cancelled by the failure are reported: %v", err)
+	}
+	if got := failedElements(t, params); !slices.Equal(got, []int{1}) {
+		t.Errorf("failed elements = %v, want [1]", got)
+	}
+	close(started)
+	var ran []int
+	for element := range started {
+		ran = append(ran, element)
+	}
+	if len(ran) > 2 {
+		t.Errorf("elements %v started after the failure", ran)
+	}
+}
+
+func TestIterableTaskStopsWhenTheContextIsCancelled(t *testing.T) {
+	for _, concurrency := range []int{1, 2} {
+		task, params, ran := newIteration(concurrency, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := task.Execute(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("concurrency %d: Execute() error = %v, want context.Canceled", concurrency, err)
+		}
+		if got := ran.sorted(); len(got) != 0 {
+			t.Errorf("concurrency %d: elements %v ran after the cancellation", concurrency, got)
+		}
+		if got := failedElements(t, params); len(got) != 0 {
+			t.Errorf("concurrency %d: failed elements = %v, want none", concurrency, got)
+		}
+	}
+}
+
+func TestIterableTaskRejectsNegativeMaxFailures(t *testing.T) {
+	task, _, _ := newIteration(1, nil, engine.WithMaxFailures(-1))
+	if err := task.Validate(); err == nil {
+		t.Error("Validate() accepted a negative max failures")
+	}
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Journal records the progress of a run in a local file: the finished tasks, the completed iteration
+// elements and the values of tracked parameters. A run resumed from a journal skips what it recorded.
+type Journal struct {
+	path  string
+	mu    sync.Mutex
+	state journalState
+}
+
+type journalState struct {
+	Rpa       string                     `json:"rpa"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+	Completed map[string]time.Time       `json:"completed"`
+	Params    map[string]json.RawMessage `json:"params"`
+}
+
+// TrackedKey is a typed parameter key whose value is saved in the journal and restored on resume
+type TrackedKey interface {
+	ParamKey
+	encode(params *Parameters) (json.RawMessage, bool, error)
+	decode(params *Parameters, raw json.RawMessage) error
+}
+
+// NewJournal creates an empty journal written to path, replacing any previous run recorded there
+func NewJournal(path string) *Journal {
+	return &Journal{
+		path: path,
+		state: journalState{
+			Completed: make(map[string]time.Time),
+			Params:    make(map[string]json.RawMessage),
+		},
+	}
+}
+
+// LoadJournal reads the journal stored at path to resume a run. A missing file yields an empty journal.
+func LoadJournal(path string) (*Journal, error) {
+	journal := NewJournal(path)
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	if err := json.Unmarshal(raw, &journal.state); err != nil {
+		return nil, fmt.Errorf("failed to decode journal %s: %w", path, err)
+	}
+	if journal.state.Completed == nil {
+		journal.state.Completed = make(map[string]time.Time)
+	}
+	if journal.state.Params == nil {
+		journal.state.Params = make(map[string]json.RawMessage)
+	}
+	return journal, nil
+}
+
+// OpenJournal loads the journal stored at path when resume is set, or starts a new one otherwise
+func OpenJournal(path string, resume bool) (*Journal, error) {
+	if resume {
+		return LoadJournal(path)
+	}
+	return NewJournal(path), nil
+}
+
+// Path returns the file the journal is written to
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Completed returns how many steps the journal recorded as completed
+func (j *Journal) Completed() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.state.Completed)
+}
+
+// IsCompleted reports whether the step was recorded as completed
+func (j *Journal) IsCompleted(step string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.state.Completed[step]
+	return ok
+}
+
+// MarkCompleted records the step as completed and saves the journal
+func (j *Journal) MarkCompleted(step string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state.Completed[step] = time.Now()
+	return j.save()
+}
+
+// Finish deletes the journal file once the run completed successfully
+func (j *Journal) Finish() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return nil
+}
+
+// bind associates the journal with the rpa, refusing a journal recorded by another rpa
+func (j *Journal) bind(rpaName string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state.Rpa != "" && j.state.Rpa != rpaName {
+		return fmt.Errorf("journal %s belongs to rpa %q, not %q", j.path, j.state.Rpa, rpaName)
+	}
+	j.state.Rpa = rpaName
+	return nil
+}
+
+// saveParams stores the current values of the tracked keys and saves the journal
+func (j *Journal) saveParams(params *Parameters, keys []TrackedKey) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, key := range keys {
+		raw, found, err := key.encode(params)
+		if err != nil {
+			return err
+		}
+		if found {
+			j.state.Params[key.Name()] = raw
+		}
+	}
+	return j.save()
+}
+
+// restoreParams writes the recorded values of the tracked keys into params
+func (j *Journal) restoreParams(params *Parameters, keys []TrackedKey) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, key := range keys {
+		raw, ok := j.state.Params[key.Name()]
+		if !ok {
+			continue
+		}
+		if err := key.decode(params, raw); err != nil {
+			return fmt.Errorf("failed to restore parameter %q from journal: %w", key.Name(), err)
+		}
+	}
+	return nil
+}
+
+// save writes the journal to a temporary file renamed over the previous one, so a crash never leaves it half written
+func (j *Journal) save() error {
+	j.state.UpdatedAt = time.Now()
+	raw, err := json.MarshalIndent(j.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+func (k Key[T]) encode(params *Parameters) (json.RawMessage, bool, error) {
+	value, found, err := k.Lookup(params)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to encode parameter %q: %w", k.name, err)
+	}
+	return raw, true, nil
+}
+
+func (k Key[T]) decode(params *Parameters, raw json.RawMessage) error {
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	k.Put(params, value)
+	return nil
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrParamType is returned when a parameter holds a value of an unexpected type
+var ErrParamType = errors.New("parameter has wrong type")
+
+// BaseURLKey is the typed key of the ParamBaseURL parameter
+var BaseURLKey = NewKey[string](ParamBaseURL)
+
+// ParamKey is a parameter key that can check the value it refers to.
+// Tasks use it to declare required parameters validated before execution.
+type ParamKey interface {
+	Name() string
+	Check(params *Parameters) error
+}
+
+// Key is a parameter key bound to the type T of its value
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates a typed key for the parameter with the given name
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// CurrentElementKey returns the key of the element being processed by an iteration over elementsKey
+func CurrentElementKey[T any](elementsKey string) Key[T] {
+	return NewKey[T](elementsKey + "_" + CurrentElement)
+}
+
+// CurrentIndexKey returns the key of the index being processed by an iteration over elementsKey
+func CurrentIndexKey(elementsKey string) Key[int] {
+	return NewKey[int](elementsKey + "_" + CurrentIndex)
+}
+
+// Name returns the parameter name
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Get returns the value of the parameter, failing with ErrMissingParam or ErrParamType
+func (k Key[T]) Get(params *Parameters) (T, error) {
+	value, found, err := k.Lookup(params)
+	if err != nil {
+		return value, err
+	}
+	if !found {
+		return value, fmt.Errorf("%w %q", ErrMissingParam, k.name)
+	}
+	return value, nil
+}
+
+// Lookup returns the value of the parameter and whether it is set. A missing parameter is not an
+// error, a value of another type fails with ErrParamType.
+func (k Key[T]) Lookup(params *Parameters) (T, bool, error) {
+	var zero T
+	raw, found := params.Lookup(k.name)
+	if !found {
+		return zero, false, nil
+	}
+	value, ok := raw.(T)
+	if !ok {
+		return zero, true, fmt.Errorf("%w: %q is %T, expected %T", ErrParamType, k.name, raw, zero)
+	}
+	return value, true, nil
+}
+
+// MustGet returns the value of the parameter and panics when it is missing or has another type.
+// Use it only where the key was already validated.
+func (k Key[T]) MustGet(params *Parameters) T {
+	value, err := k.Get(params)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Put sets the value of the parameter in the current scope
+func (k Key[T]) Put(params *Parameters, value T) {
+	params.Put(k.name, value)
+}
+
+// Check validates that the parameter is set with a value of type T
+func (k Key[T]) Check(params *Parameters) error {
+	_, err := k.Get(params)
+	return err
+}
@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+)
+
+// Listener observes the execution of an rpa and of every task nested in it. Hooks are called synchronously
+// by the goroutine running the task, concurrently when an iteration runs elements in parallel, so they
+// must be safe for concurrent use and return quickly. Embed NopListener to implement only some hooks.
+type Listener interface {
+	// BeforeTask is called before a task is validated and executed
+	BeforeTask(ctx context.Context, event TaskEvent)
+	// AfterTask is called once a task finished, successfully or not
+	AfterTask(ctx context.Context, event TaskEvent)
+	// OnError is called once when the rpa stops because of a failing task
+	OnError(ctx context.Context, event TaskEvent)
+	// BeforeElement is called before an iterable task processes an element
+	BeforeElement(ctx context.Context, event ElementEvent)
+	// AfterElement is called once an iterable task processed, or skipped, an element
+	AfterElement(ctx context.Context, event ElementEvent)
+	// OnRequest is called before an HTTP task sends its request
+	OnRequest(ctx context.Context, event RequestEvent)
+	// OnRetry is called when an attempt of an HTTP request failed and is retried
+	OnRetry(ctx context.Context, event RetryEvent)
+	// OnResponse is called once an HTTP task received its response, or gave up on it
+	OnResponse(ctx context.Context, event ResponseEvent)
+}
+
+// TaskEvent describes a task of the run. Step is the path of the task in the task tree, such as
+// "process_quizes[12]/process_quiz/start_quiz". Duration and Err are only set once the task finished.
+type TaskEvent struct {
+	Rpa      string
+	Task     string
+	Step     string
+	Duration time.Duration
+	Err      error
+}
+
+// ElementEvent describes an element of an iterable task. Skipped is set for elements completed in a
+// previous run. Duration and Err are only set once the element was processed.
+type ElementEvent struct {
+	Rpa      string
+	Task     string
+	Step     string
+	Index    int
+	Element  any
+	Skipped  bool
+	Duration time.Duration
+	Err      error
+}
+
+// RequestEvent describes the request sent by an HTTP task
+type RequestEvent struct {
+	Rpa     string
+	Task    string
+	Step    string
+	Method  httprequest.HTTPMethod
+	URL     string
+	Headers httprequest.Headers
+	Body    []byte
+}
+
+// RetryEvent describes a failed attempt of an HTTP request that is about to be retried
+type RetryEvent struct {
+	Rpa     string
+	Task    string
+	Step    string
+	Attempt int
+	Delay   time.Duration
+	Err     error
+}
+
+// ResponseEvent describes the outcome of the request of an HTTP task. StatusCode is zero when no
+// response was received.
+type ResponseEvent struct {
+	Request    RequestEvent
+	StatusCode int
+	Attempts   int
+	Duration   time.Duration
+	Err        error
+}
+
+// NopListener implements every Listener hook as a no-op
+type NopListener struct{}
+
+func (NopListener) BeforeTask(context.Context, TaskEvent)       {}
+func (NopListener) AfterTask(context.Context, TaskEvent)        {}
+func (NopListener) OnError(context.Context, TaskEvent)          {}
+func (NopListener) BeforeElement(context.Context, ElementEvent) {}
+func (NopListener) AfterElement(context.Context, ElementEvent)  {}
+func (NopListener) OnRequest(context.Context, RequestEvent)     {}
+func (NopListener) OnRetry(context.Context, RetryEvent)         {}
+func (NopListener) OnResponse(context.Context, ResponseEvent)   {}
+
+// listeners dispatches every hook to each listener, in registration order
+type listeners []Listener
+
+func (ls listeners) BeforeTask(ctx context.Context, event TaskEvent) {
+	for _, l := range ls {
+		l.BeforeTask(ctx, event)
+	}
+}
+
+func (ls listeners) AfterTask(ctx context.Context, event TaskEvent) {
+	for _, l := range ls {
+		l.AfterTask(ctx, event)
+	}
+}
+
+func (ls listeners) OnError(ctx context.Context, event TaskEvent) {
+	for _, l := range ls {
+		l.OnError(ctx, event)
+	}
+}
+
+func (ls listeners) BeforeElement(ctx context.Context, event ElementEvent) {
+	for _, l := range ls {
+		l.BeforeElement(ctx, event)
+	}
+}
+
+func (ls listeners) AfterElement(ctx context.Context, event ElementEvent) {
+	for _, l := range ls {
+		l.AfterElement(ctx, event)
+	}
+}
+
+func (ls listeners) OnRequest(ctx context.Context, event RequestEvent) {
+	for _, l := range ls {
+		l.OnRequest(ctx, event)
+	}
+}
+
+func (ls listeners) OnRetry(ctx context.Context, event RetryEvent) {
+	for _, l := range ls {
+		l.OnRetry(ctx, event)
+	}
+}
+
+func (ls listeners) OnResponse(ctx context.Context, event ResponseEvent) {
+	for _, l := range ls {
+		l.OnResponse(ctx, event)
+	}
+}
+
+// observeTask runs fn as the execution of the nested task: the step path is extended with the task
+// name and the listeners of the run are notified before and after it
+func observeTask(ctx context.Context, task Task, fn func(ctx context.Context) error) error {
+	ctx = withStep(ctx, task.Name())
+	ls := listenersFrom(ctx)
+	event := TaskEvent{Rpa: rpaName(ctx), Task: task.Name(), Step: StepPath(ctx)}
+	ls.BeforeTask(ctx, event)
+	start := time.Now()
+	err := fn(ctx)
+	event.Duration = time.Since(start)
+	event.Err = err
+	ls.AfterTask(ctx, event)
+	return err
+}
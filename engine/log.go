@@ -1,25 +1,160 @@
 package engine
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
 )
 
 type Logger interface {
+	Debug(format string, args ...any)
 	Info(format string, args ...any)
 	Warn(format string, args ...any)
 	Error(format string, args ...any)
 }
 
+// FieldLogger is a Logger able to attach structured fields, given as alternating keys and values, to its records
+type FieldLogger interface {
+	Logger
+	With(args ...any) Logger
+}
+
+// DefaultLogger writes through the default slog logger configured by ConfigureLogging. Its records carry the
+// prefix naming the component, such as "Iterable Task - process_quizes", under the "logger" field.
 type DefaultLogger struct {
 	prefix string
+	fields []any
+}
+
+// NewLogger creates a DefaultLogger for the named component
+func NewLogger(prefix string) *DefaultLogger {
+	return &DefaultLogger{prefix: prefix}
+}
+
+func (l *DefaultLogger) Debug(format string, args ...any) {
+	l.log(slog.LevelDebug, format, args)
 }
 
 func (l *DefaultLogger) Info(format string, args ...any) {
-	fmt.Printf("[%s] INFO: %s\n", l.prefix, fmt.Sprintf(format, args...))
+	l.log(slog.LevelInfo, format, args)
 }
+
 func (l *DefaultLogger) Warn(format string, args ...any) {
-	fmt.Printf("[%s] WARN: %s\n", l.prefix, fmt.Sprintf(format, args...))
+	l.log(slog.LevelWarn, format, args)
 }
+
 func (l *DefaultLogger) Error(format string, args ...any) {
-	fmt.Printf("[%s] ERROR: %s\n", l.prefix, fmt.Sprintf(format, args...))
+	l.log(slog.LevelError, format, args)
+}
+
+// With returns a logger adding the fields to every record. A key already set is replaced.
+func (l *DefaultLogger) With(args ...any) Logger {
+	return &DefaultLogger{prefix: l.prefix, fields: mergeFields(l.fields, args)}
+}
+
+func (l *DefaultLogger) log(level slog.Level, format string, args []any) {
+	logger := slog.Default()
+	if !logger.Enabled(context.Background(), level) {
+		return
+	}
+	attrs := make([]any, 0, len(l.fields)+2)
+	if l.prefix != "" {
+		attrs = append(attrs, "logger", l.prefix)
+	}
+	attrs = append(attrs, l.fields...)
+	logger.Log(context.Background(), level, fmt.Sprintf(format, args...), attrs...)
+}
+
+// mergeFields appends the key value pairs of extra to fields, replacing the keys already present
+func mergeFields(fields, extra []any) []any {
+	merged := make([]any, 0, len(fields)+len(extra))
+	merged = append(merged, fields...)
+	for i := 0; i+1 < len(extra); i += 2 {
+		replaced := false
+		for j := 0; j+1 < len(merged); j += 2 {
+			if merged[j] == extra[i] {
+				merged[j+1] = extra[i+1]
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, extra[i], extra[i+1])
+		}
+	}
+	return merged
+}
+
+// ContextLogger returns the logger enriched with the run fields carried by the context: the rpa name,
+// the step path and the fields of the iteration elements being processed, such as "element_index".
+// Task callbacks use it to log with the same fields as the engine. Loggers that are not FieldLoggers
+// are returned unchanged.
+func ContextLogger(ctx context.Context, logger Logger) Logger {
+	fieldLogger, ok := logger.(FieldLogger)
+	if !ok {
+		return logger
+	}
+	var fields []any
+	if name := rpaName(ctx); name != "" {
+		fields = append(fields, "rpa", name)
+	}
+	if step := StepPath(ctx); step != "" {
+		fields = append(fields, "step", step)
+	}
+	fields = append(fields, logFields(ctx)...)
+	if len(fields) == 0 {
+		return logger
+	}
+	return fieldLogger.With(fields...)
+}
+
+// LogConfig selects the level, format and output of the logs
+type LogConfig struct {
+	Level slog.Level
+	// Format is "text" or "json"
+	Format string
+	Output io.Writer
+}
+
+// LogConfigFromEnv reads the logging configuration from LOG_LEVEL (debug, info, warn or error, default info)
+// and LOG_FORMAT (text or json, default text). Logs are written to stderr.
+func LogConfigFromEnv() (LogConfig, error) {
+	cfg := LogConfig{Level: slog.LevelInfo, Format: "text", Output: os.Stderr}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := cfg.Level.UnmarshalText([]byte(level)); err != nil {
+			return cfg, fmt.Errorf("invalid LOG_LEVEL %q: %w", level, err)
+		}
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		cfg.Format = strings.ToLower(format)
+	}
+	if cfg.Format != "text" && cfg.Format != "json" {
+		return cfg, fmt.Errorf("invalid LOG_FORMAT %q: expected text or json", cfg.Format)
+	}
+	return cfg, nil
+}
+
+// ConfigureLogging installs the slog logger described by cfg as the default one, used by every DefaultLogger,
+// by the slog package functions and by the standard log package. The secrets are redacted from every
+// record, see secret.Redact.
+func ConfigureLogging(cfg LogConfig) *slog.Logger {
+	output := cfg.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	options := &slog.HandlerOptions{Level: cfg.Level}
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(output, options)
+	} else {
+		handler = slog.NewTextHandler(output, options)
+	}
+	logger := slog.New(secret.NewHandler(handler))
+	slog.SetDefault(logger)
+	return logger
 }
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// LoginConfig describes how a login task authenticates on the website
+type LoginConfig struct {
+	// URL receives the credentials. It is a Go template rendered from the task parameters.
+	URL      string
+	Username string
+	Password secret.Secret
+	// UsernameField and PasswordField name the credentials in the body, "username" and "password" by default
+	UsernameField string
+	PasswordField string
+	// Form posts the credentials as an HTML form would, instead of a JSON object. Redirects are followed.
+	Form bool
+	// TokenPath is the extract path of the token in the JSON response, see Extract. When empty the website
+	// is expected to authenticate the run with the cookies it sets.
+	TokenPath string
+	// TokenPrefix is prepended to the token, such as "Bearer "
+	TokenPrefix string
+}
+
+// LoginConfigFromEnv reads the login from the LOGIN_URL, LOGIN_USERNAME, LOGIN_PASSWORD, LOGIN_FORM,
+// LOGIN_TOKEN_PATH and LOGIN_TOKEN_PREFIX environment variables. It returns nil when LOGIN_URL is not set.
+func LoginConfigFromEnv() (*LoginConfig, error) {
+	loginURL := os.Getenv("LOGIN_URL")
+	if loginURL == "" {
+		return nil, nil
+	}
+	cfg := &LoginConfig{
+		URL:         loginURL,
+		Username:    os.Getenv("LOGIN_USERNAME"),
+		Password:    secret.New(os.Getenv("LOGIN_PASSWORD")),
+		TokenPath:   os.Getenv("LOGIN_TOKEN_PATH"),
+		TokenPrefix: os.Getenv("LOGIN_TOKEN_PREFIX"),
+	}
+	if value := os.Getenv("LOGIN_FORM"); value != "" {
+		form, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOGIN_FORM: %w", err)
+		}
+		cfg.Form = form
+	}
+	return cfg, nil
+}
+
+// NewLoginTask creates a task posting the credentials to the login URL. The cookies set by the website are
+// kept in the session of the client sending the request, see httprequest.Client.WithSession, and the token
+// found at cfg.TokenPath is stored in that session for the host of the login URL. Every later request sent
+// through the client then carries them. The pre request and post extract functions of the options run
+// after the session check and after the token is stored. During a dry run the credentials are not sent,
+// and a response without token leaves the session as it is.
+func NewLoginTask(name string, cfg LoginConfig, headers httprequest.Headers, params *Parameters, options ...Option) *HTTPTask {
+	usernameField, passwordField := cfg.UsernameField, cfg.PasswordField
+	if usernameField == "" {
+		usernameField = "username"
+	}
+	if passwordField == "" {
+		passwordField = "password"
+	}
+	encoder := func(*Parameters) (httprequest.Body, error) {
+		if cfg.Form {
+			return httprequest.FormBody(url.Values{usernameField: {cfg.Username}, passwordField: {cfg.Password.Reveal()}}), nil
+		}
+		return httprequest.JSONBody(map[string]string{usernameField: cfg.Username, passwordField: cfg.Password.Reveal()})
+	}
+
+	task := NewHTTPTask(name, httprequest.POST, headers, params, append(options,
+		WithURLTemplate(cfg.URL),
+		WithBodyEncoder(encoder),
+	)...)
+	preRequest, postExtract := task.preRequestFunc, task.postExtractFunc
+	task.preRequestFunc = func(ctx context.Context) error {
+		if task.httpClient(ctx).Session() == nil {
+			return errors.New("the client has no session, see httprequest.Client.WithSession")
+		}
+		if preRequest != nil {
+			return preRequest(ctx)
+		}
+		return nil
+	}
+	task.postExtractFunc = func(ctx context.Context, resp *http.Response, task *HTTPTask) error {
+		if cfg.TokenPath != "" {
+			if err := storeToken(ctx, task, resp, cfg); err != nil {
+				return err
+			}
+		}
+		if postExtract != nil {
+			return postExtract(ctx, resp, task)
+		}
+		return nil
+	}
+	return task
+}
+
+// storeToken stores the token of the login response in the session of the task client
+func storeToken(ctx context.Context, task *HTTPTask, resp *http.Response, cfg LoginConfig) error {
+	logger := ContextLogger(ctx, task.Logger)
+	token, err := extractToken(resp, cfg.TokenPath)
+	if err != nil {
+		if httprequest.IsDryRun(ctx) {
+			logger.Info("Dry run: login not sent, no token stored")
+			return nil
+		}
+		return err
+	}
+	loginURL, err := url.Parse(task.URL)
+	if err != nil {
+		return fmt.Errorf("invalid login URL: %w", err)
+	}
+	task.httpClient(ctx).Session().SetToken(loginURL.Host, cfg.TokenPrefix+token)
+	logger.Info("Logged in, token stored for %s", loginURL.Host)
+	return nil
+}
+
+// extractToken returns the string found at the path of the JSON response
+func extractToken(resp *http.Response, path string) (string, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return "", err
+	}
+	var body []byte
+	if body, resp.Body, err = readAndRestoreBody(resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read login response: %w", err)
+	}
+	raw, ok, err := lookupPath(body, segments)
+	if err != nil {
+		return "", fmt.Errorf("token %q: %w", path, err)
+	}
+	if !ok {
+		return "", fmt.Errorf("no token at %q in the login response", path)
+	}
+	token, err := decodeRaw[string](raw)
+	if err != nil || token == "" {
+		return "", fmt.Errorf("the token at %q is not a string", path)
+	}
+	return token, nil
+}
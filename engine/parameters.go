@@ -1,12 +1,122 @@
 package engine
 
-// Parameters hold the parameters for tasks
-type Parameters map[string]any
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
 
-func (params Parameters) Put(key string, value any) {
-	params[key] = value
+// ErrNoParentScope is returned when publishing from parameters that have no parent scope
+var ErrNoParentScope = errors.New("no parent scope")
+
+// Parameters hold the parameters for tasks.
+// Parameters are organised in scopes: a scope reads through to its parent scope, while writes
+// stay local and shadow the parent keys. Scopes are discarded when the step that created them ends,
+// unless their values are explicitly published to the parent scope.
+type Parameters struct {
+	mu     sync.RWMutex
+	parent *Parameters
+	values map[string]any
+}
+
+// NewParameters creates a root scope holding a copy of the given values
+func NewParameters(values map[string]any) *Parameters {
+	params := &Parameters{values: make(map[string]any, len(values))}
+	for key, value := range values {
+		params.values[key] = value
+	}
+	return params
+}
+
+// Put sets the value of key in the current scope
+func (params *Parameters) Put(key string, value any) {
+	params.mu.Lock()
+	defer params.mu.Unlock()
+	params.values[key] = value
+}
+
+// Get returns the value of key from the nearest scope that defines it, or nil
+func (params *Parameters) Get(key string) any {
+	value, _ := params.Lookup(key)
+	return value
+}
+
+// Lookup returns the value of key from the nearest scope that defines it and whether it was found
+func (params *Parameters) Lookup(key string) (any, bool) {
+	params.mu.RLock()
+	value, ok := params.values[key]
+	parent := params.parent
+	params.mu.RUnlock()
+	if ok {
+		return value, true
+	}
+	if parent != nil {
+		return parent.Lookup(key)
+	}
+	return nil, false
+}
+
+// Has reports whether key is defined in this scope or any of its parents
+func (params *Parameters) Has(key string) bool {
+	_, ok := params.Lookup(key)
+	return ok
+}
+
+// NewScope creates a detached child scope. The child sees the keys of params, but its own writes
+// are invisible to params. It is used to give concurrent work isolated parameters.
+func (params *Parameters) NewScope() *Parameters {
+	return &Parameters{parent: params, values: make(map[string]any)}
+}
+
+// EnterScope starts a child scope in place: every holder of params reads and writes the new scope
+// until ExitScope discards it. It is used by sequential steps whose tasks share the parameters.
+func (params *Parameters) EnterScope() {
+	params.mu.Lock()
+	defer params.mu.Unlock()
+	params.parent = &Parameters{parent: params.parent, values: params.values}
+	params.values = make(map[string]any)
+}
+
+// ExitScope discards the scope started by the last EnterScope call, restoring the parent scope
+func (params *Parameters) ExitScope() {
+	params.mu.Lock()
+	defer params.mu.Unlock()
+	if params.parent == nil {
+		return
+	}
+	params.values = params.parent.values
+	params.parent = params.parent.parent
+}
+
+// Publish copies the current values of the given keys to the parent scope, so they outlive the current one
+func (params *Parameters) Publish(keys ...string) error {
+	params.mu.RLock()
+	parent := params.parent
+	params.mu.RUnlock()
+	if parent == nil {
+		return ErrNoParentScope
+	}
+	for _, key := range keys {
+		value, ok := params.Lookup(key)
+		if !ok {
+			return fmt.Errorf("cannot publish %q: %w", key, ErrMissingParam)
+		}
+		parent.Put(key, value)
+	}
+	return nil
 }
 
-func (params Parameters) Get(key string) any {
-	return params[key]
+// Snapshot returns every visible key with its value, the nearest scope winning over its parents
+func (params *Parameters) Snapshot() map[string]any {
+	params.mu.RLock()
+	parent := params.parent
+	snapshot := make(map[string]any, len(params.values))
+	if parent != nil {
+		snapshot = parent.Snapshot()
+	}
+	for key, value := range params.values {
+		snapshot[key] = value
+	}
+	params.mu.RUnlock()
+	return snapshot
 }
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -10,9 +11,11 @@ var (
 )
 
 type PipelinedTasks struct {
-	tasks  []Task
-	name   string
-	Logger Logger
+	tasks   []Task
+	name    string
+	Logger  Logger
+	scope   *Parameters
+	publish []string
 }
 
 func NewPipelinedTasks(name string, tasks []Task) *PipelinedTasks {
@@ -34,17 +37,48 @@ func (p *PipelinedTasks) Validate() error {
 	return nil
 }
 
-func (p *PipelinedTasks) Execute() error {
-	p.Logger.Info("Executing pipelined tasks...")
+func (p *PipelinedTasks) Execute(ctx context.Context) error {
+	logger := ContextLogger(ctx, p.Logger)
+	logger.Info("Executing pipelined tasks...")
+	if p.scope != nil {
+		p.scope.EnterScope()
+		defer p.scope.ExitScope()
+	}
 	for _, task := range p.tasks {
-		if err := task.Execute(); err != nil {
-			return fmt.Errorf("HTTP request failed in pipeline task '%s': %w", task.Name(), err)
+		if err := ctx.Err(); err != nil {
+			logger.Warn("Pipeline interrupted before task %s", task.Name())
+			return fmt.Errorf("pipeline '%s' interrupted before task '%s': %w", p.name, task.Name(), err)
+		}
+		err := observeTask(ctx, task, func(ctx context.Context) error {
+			if err := task.Validate(); err != nil {
+				return fmt.Errorf("validation failed in pipeline task '%s': %w", task.Name(), err)
+			}
+			if err := task.Execute(ctx); err != nil {
+				return fmt.Errorf("HTTP request failed in pipeline task '%s': %w", task.Name(), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if p.scope != nil && len(p.publish) > 0 {
+		if err := p.scope.Publish(p.publish...); err != nil {
+			return fmt.Errorf("pipeline '%s': %w", p.name, err)
 		}
 	}
-	p.Logger.Info("Finished pipelined tasks")
+	logger.Info("Finished pipelined tasks")
 	return nil
 }
 
+// Scoped runs the pipeline inside a child scope of params, so the values written by its tasks are
+// discarded when it finishes. The keys listed in publish are copied to the parent scope on success.
+func (p *PipelinedTasks) Scoped(params *Parameters, publish ...string) *PipelinedTasks {
+	p.scope = params
+	p.publish = publish
+	return p
+}
+
 // AddTask adds a task to the pipeline
 func (p *PipelinedTasks) AddTask(task Task) *PipelinedTasks {
 	p.tasks = append(p.tasks, task)
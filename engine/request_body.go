@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// BodyEncoder builds the request body of an HTTPTask from the task parameters before each execution
+type BodyEncoder func(params *Parameters) (httprequest.Body, error)
+
+// WithBodyEncoder sets the encoder building the request body and its Content-Type header, replacing the
+// body template and the RequestBody field
+func WithBodyEncoder(encoder BodyEncoder) Option {
+	return func(t *HTTPTask) {
+		t.bodyEncoder = encoder
+	}
+}
+
+// WithJSONBody sends the value marshalled as JSON
+func WithJSONBody(value any) Option {
+	return WithBodyEncoder(func(*Parameters) (httprequest.Body, error) {
+		return httprequest.JSONBody(value)
+	})
+}
+
+// WithJSONBodyParam sends the value of the parameter marshalled as JSON. A secret.Secret parameter sends
+// its value; secrets nested in the value are marshalled as secret.Redacted.
+func WithJSONBodyParam(key string) Option {
+	return WithBodyEncoder(func(params *Parameters) (httprequest.Body, error) {
+		value, ok := params.Lookup(key)
+		if !ok {
+			return httprequest.Body{}, fmt.Errorf("JSON body: %w %q", ErrMissingParam, key)
+		}
+		if s, ok := value.(secret.Secret); ok {
+			value = s.Reveal()
+		}
+		return httprequest.JSONBody(value)
+	})
+}
+
+// WithFormBody sends the fields as an application/x-www-form-urlencoded body. Field values are Go
+// templates rendered from the task parameters.
+func WithFormBody(fields map[string]string) Option {
+	return func(t *HTTPTask) {
+		templates := t.templates.parseFields("form", fields)
+		t.bodyEncoder = func(params *Parameters) (httprequest.Body, error) {
+			values, err := renderFields(templates, params)
+			if err != nil {
+				return httprequest.Body{}, err
+			}
+			return httprequest.FormBody(values), nil
+		}
+	}
+}
+
+// WithMultipartBody sends the fields and the files as a multipart/form-data body. Field values are Go
+// templates rendered from the task parameters.
+func WithMultipartBody(fields map[string]string, files ...httprequest.MultipartFile) Option {
+	return func(t *HTTPTask) {
+		templates := t.templates.parseFields("multipart", fields)
+		t.bodyEncoder = func(params *Parameters) (httprequest.Body, error) {
+			values, err := renderFields(templates, params)
+			if err != nil {
+				return httprequest.Body{}, err
+			}
+			return httprequest.MultipartBody(values, files...)
+		}
+	}
+}
+
+func (rt *requestTemplates) parseFields(kind string, fields map[string]string) map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(fields))
+	for name, text := range fields {
+		templates[name] = rt.parse(kind+" field "+name, text)
+	}
+	return templates
+}
+
+func renderFields(templates map[string]*template.Template, params *Parameters) (url.Values, error) {
+	values := make(url.Values, len(templates))
+	for name, tmpl := range templates {
+		value, err := renderTemplate(tmpl, params)
+		if err != nil {
+			return nil, err
+		}
+		values.Set(name, value)
+	}
+	return values, nil
+}
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -14,11 +15,17 @@ const (
 // Rpa represents a complete workflow consisting of multiple tasks
 type Rpa struct {
 	tasks          []Task
-	params         Parameters
+	params         *Parameters
 	defaultHeaders httprequest.Headers
 	logger         Logger
 	name           string
 	baseURL        string
+	journal        *Journal
+	trackedKeys    []TrackedKey
+	listeners      []Listener
+	dryRun         *httprequest.DryRun
+	client         *httprequest.Client
+	login          *LoginConfig
 }
 
 // NewRpa creates a new rpa with the given name
@@ -27,9 +34,9 @@ func NewRpa(name, baseURL string, defaultHeaders httprequest.Headers) *Rpa {
 		name:           name,
 		baseURL:        baseURL,
 		tasks:          []Task{},
-		logger:         &DefaultLogger{fmt.Sprintf("RPA - %s", name)},
+		logger:         &DefaultLogger{prefix: fmt.Sprintf("RPA - %s", name)},
 		defaultHeaders: defaultHeaders,
-		params:         make(Parameters),
+		params:         NewParameters(nil),
 	}
 }
 
@@ -40,40 +47,151 @@ func (j *Rpa) AddTask(task Task) *Rpa {
 }
 
 // SetParams sets the parameters for the rpa
-func (j *Rpa) SetParams(params Parameters) *Rpa {
+func (j *Rpa) SetParams(params *Parameters) *Rpa {
 	j.params = params
 	return j
 }
 
-func (j *Rpa) GetParams() Parameters {
+func (j *Rpa) GetParams() *Parameters {
 	return j.params
 }
 
 // AddParam adds a single parameter to the rpa
 func (j *Rpa) AddParam(key string, value any) *Rpa {
-	j.params[key] = value
+	j.params.Put(key, value)
 	return j
 }
 
-// Execute runs all tasks in the rpa sequentially, validating each task before execution
-func (j *Rpa) Execute() error {
+// SetJournal records the progress of the rpa in the journal: finished tasks, completed iteration elements
+// and the values of the tracked keys. Work already recorded in the journal is skipped, so loading the
+// journal of an interrupted run resumes it. The journal file is removed once the rpa completes.
+func (j *Rpa) SetJournal(journal *Journal, trackedKeys ...TrackedKey) *Rpa {
+	j.journal = journal
+	j.trackedKeys = trackedKeys
+	return j
+}
+
+// SetDryRun executes the rpa without changing anything on the server: mutating requests are logged but
+// not sent, see httprequest.DryRun. Every task still runs, and a dry run neither reads nor writes the journal.
+func (j *Rpa) SetDryRun(dryRun *httprequest.DryRun) *Rpa {
+	j.dryRun = dryRun
+	return j
+}
+
+// SetClient sends the requests of every HTTP task of the rpa with the client, unless the task has its
+// own, see WithClient. Without it the tasks use the http_request default client.
+func (j *Rpa) SetClient(client *httprequest.Client) *Rpa {
+	j.client = client
+	return j
+}
+
+// SetLogin logs the rpa in before its first task with a login task named "login", see NewLoginTask. The
+// login is validated, reported to the listeners and follows the dry run like the other tasks, but it runs
+// again when a journal is resumed, since the session of the interrupted run is gone.
+func (j *Rpa) SetLogin(cfg LoginConfig) *Rpa {
+	j.login = &cfg
+	return j
+}
+
+// AddListener registers a listener notified of the execution of every task of the rpa, including the
+// tasks nested in pipelines, iterations and branches, and of every HTTP request they send
+func (j *Rpa) AddListener(listener Listener) *Rpa {
+	j.listeners = append(j.listeners, listener)
+	return j
+}
+
+// Execute runs all tasks in the rpa sequentially, validating each task before execution.
+// When the context is cancelled the rpa stops between tasks and the returned error names the task where it stopped.
+func (j *Rpa) Execute(ctx context.Context) error {
 	if j == nil {
 		return errors.New("rpa is nil")
 	}
 
-	j.logger.Info("Starting RPA...")
-	for _, task := range j.tasks {
+	ctx = withRpa(ctx, j.name)
+	ctx = withListeners(ctx, j.listeners)
+	if j.client != nil {
+		ctx = withClient(ctx, j.client)
+	}
+	logger := ContextLogger(ctx, j.logger)
+	logger.Info("Starting RPA...")
+	journal := j.journal
+	if j.dryRun != nil {
+		logger.Info("Dry run: mutating requests will not be sent")
+		ctx = httprequest.WithDryRun(ctx, j.dryRun)
+		journal = nil
+	}
+	if journal != nil {
+		if err := journal.bind(j.name); err != nil {
+			return err
+		}
+		if err := journal.restoreParams(j.params, j.trackedKeys); err != nil {
+			return err
+		}
+		if completed := journal.Completed(); completed > 0 {
+			logger.Info("Resuming from journal %s with %d completed steps", journal.Path(), completed)
+		}
+		ctx = withJournal(ctx, journal)
+	}
+
+	tasks := j.tasks
+	var login Task
+	if j.login != nil {
+		login = NewLoginTask("login", *j.login, nil, j.params)
+		tasks = append([]Task{login}, j.tasks...)
+	}
+	for i, task := range tasks {
 		taskName := task.Name()
-		if err := task.Validate(); err != nil {
-			j.logger.Error("Validation failed for task %s - %v", taskName, err)
-			return fmt.Errorf("validation failed for task %s: %w", taskName, err)
+		journaled := journal != nil && task != login
+		if journaled && journal.IsCompleted(taskName) {
+			logger.Info("Skipping task %s, completed in a previous run", taskName)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			logger.Warn("RPA stopped before task %s (%d/%d): %v", taskName, i+1, len(tasks), err)
+			return fmt.Errorf("rpa stopped before task %s (%d/%d): %w", taskName, i+1, len(tasks), err)
 		}
-		if err := task.Execute(); err != nil {
-			j.logger.Error("Task [%s] execution failed %v", taskName, err)
-			return fmt.Errorf("execution failed for task %s: %w", taskName, err)
+		err := observeTask(ctx, task, func(ctx context.Context) error {
+			if err := task.Validate(); err != nil {
+				logger.Error("Validation failed for task %s - %v", taskName, err)
+				return fmt.Errorf("validation failed for task %s: %w", taskName, err)
+			}
+			if err := task.Execute(ctx); err != nil {
+				if ctx.Err() != nil {
+					logger.Warn("RPA interrupted during task %s (%d/%d): %v", taskName, i+1, len(tasks), err)
+					return fmt.Errorf("rpa interrupted during task %s (%d/%d): %w", taskName, i+1, len(tasks), err)
+				}
+				logger.Error("Task [%s] execution failed %v", taskName, err)
+				return fmt.Errorf("execution failed for task %s: %w", taskName, err)
+			}
+			return nil
+		})
+		if err != nil {
+			listenersFrom(ctx).OnError(ctx, TaskEvent{Rpa: j.name, Task: taskName, Step: taskName, Err: err})
+			return err
+		}
+		if journaled {
+			if err := j.checkpoint(taskName); err != nil {
+				return err
+			}
+		}
+	}
+	if journal != nil {
+		if err := journal.Finish(); err != nil {
+			logger.Warn("Could not remove journal: %v", err)
 		}
 	}
 
-	j.logger.Info("RPA completed successfully")
+	logger.Info("RPA completed successfully")
+	return nil
+}
+
+// checkpoint records the finished task and the tracked parameter values in the journal
+func (j *Rpa) checkpoint(taskName string) error {
+	if err := j.journal.saveParams(j.params, j.trackedKeys); err != nil {
+		return fmt.Errorf("failed to save journal after task %s: %w", taskName, err)
+	}
+	if err := j.journal.MarkCompleted(taskName); err != nil {
+		return fmt.Errorf("failed to save journal after task %s: %w", taskName, err)
+	}
 	return nil
 }
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+)
+
+type journalContextKey struct{}
+
+type stepContextKey struct{}
+
+type listenersContextKey struct{}
+
+type rpaContextKey struct{}
+
+type logFieldsContextKey struct{}
+
+type clientContextKey struct{}
+
+// WithLogFields adds structured fields, as alternating keys and values, such as "course_id", 12, to the
+// logs written through ContextLogger with the returned context. Fields already set are replaced.
+func WithLogFields(ctx context.Context, args ...any) context.Context {
+	return context.WithValue(ctx, logFieldsContextKey{}, mergeFields(logFields(ctx), args))
+}
+
+// logFields returns the structured log fields carried by the context
+func logFields(ctx context.Context) []any {
+	fields, _ := ctx.Value(logFieldsContextKey{}).([]any)
+	return fields
+}
+
+// withRpa records the name of the rpa running the tasks executed with the returned context
+func withRpa(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, rpaContextKey{}, name)
+}
+
+// rpaName returns the name of the rpa running the task, or "" outside of an rpa
+func rpaName(ctx context.Context) string {
+	name, _ := ctx.Value(rpaContextKey{}).(string)
+	return name
+}
+
+// withListeners makes the listeners available to the tasks executed with the returned context
+func withListeners(ctx context.Context, ls []Listener) context.Context {
+	return context.WithValue(ctx, listenersContextKey{}, listeners(ls))
+}
+
+// listenersFrom returns the listeners of the run, empty when nobody listens
+func listenersFrom(ctx context.Context) listeners {
+	ls, _ := ctx.Value(listenersContextKey{}).(listeners)
+	return ls
+}
+
+// withClient makes the client available to the HTTP tasks executed with the returned context
+func withClient(ctx context.Context, client *httprequest.Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// clientFrom returns the client of the run, nil meaning the http_request default client
+func clientFrom(ctx context.Context) *httprequest.Client {
+	client, _ := ctx.Value(clientContextKey{}).(*httprequest.Client)
+	return client
+}
+
+// withJournal makes the journal available to the tasks executed with the returned context
+func withJournal(ctx context.Context, journal *Journal) context.Context {
+	return context.WithValue(ctx, journalContextKey{}, journal)
+}
+
+// journalFrom returns the journal of the run, or nil when the run is not journaled
+func journalFrom(ctx context.Context) *Journal {
+	journal, _ := ctx.Value(journalContextKey{}).(*Journal)
+	return journal
+}
+
+// withStep appends the step to the path identifying the position of a task in the task tree
+func withStep(ctx context.Context, step string) context.Context {
+	if parent := StepPath(ctx); parent != "" {
+		step = parent + "/" + step
+	}
+	return context.WithValue(ctx, stepContextKey{}, step)
+}
+
+// StepPath returns the path of the task being executed in the task tree, such as "process_quizes[12]/process_quiz"
+func StepPath(ctx context.Context) string {
+	path, _ := ctx.Value(stepContextKey{}).(string)
+	return path
+}
+
+// withElementStep suffixes the step path of the iterable task, or its name at the root, with the element.
+// Elements are named by their value when it is a string or a number, which stays stable when the list is
+// fetched again, and by their index otherwise.
+func withElementStep(ctx context.Context, name string, element any, index int) context.Context {
+	path := StepPath(ctx)
+	if path == "" {
+		path = name
+	}
+	switch reflect.ValueOf(element).Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		path = fmt.Sprintf("%s[%v]", path, element)
+	default:
+		path = fmt.Sprintf("%s[#%d]", path, index)
+	}
+	return context.WithValue(ctx, stepContextKey{}, path)
+}
@@ -1,8 +1,10 @@
 package engine
 
+import "context"
+
 // Task represents a single operation within a rpa
 type Task interface {
-	Execute() error
+	Execute(ctx context.Context) error
 	Validate() error
 	Name() string
 }
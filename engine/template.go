@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// ErrMissingParam is returned when a template references a parameter that is not set
+var ErrMissingParam = errors.New("missing parameter")
+
+// requestTemplates holds the templates used to build the request of an HTTPTask
+type requestTemplates struct {
+	url     *template.Template
+	headers map[string]*template.Template
+	query   map[string]*template.Template
+	body    *template.Template
+	err     error
+}
+
+// WithURLTemplate sets a Go template rendered from the task parameters into the request URL before each execution.
+// Example: "{{.baseUrl}}api/quiz/{{.quizIds_currentElement}}/start"
+func WithURLTemplate(text string) Option {
+	return func(t *HTTPTask) {
+		t.templates.url = t.templates.parse("url", text)
+	}
+}
+
+// WithHeaderTemplate sets a Go template rendered from the task parameters into the given request header
+func WithHeaderTemplate(key, text string) Option {
+	return func(t *HTTPTask) {
+		if t.templates.headers == nil {
+			t.templates.headers = make(map[string]*template.Template)
+		}
+		t.templates.headers[key] = t.templates.parse("header "+key, text)
+	}
+}
+
+// WithQueryParam adds a query parameter to the request URL, its value being a Go template rendered from
+// the task parameters. Example: WithQueryParam("page", "{{.page}}")
+func WithQueryParam(name, text string) Option {
+	return func(t *HTTPTask) {
+		if t.templates.query == nil {
+			t.templates.query = make(map[string]*template.Template)
+		}
+		t.templates.query[name] = t.templates.parse("query "+name, text)
+	}
+}
+
+// WithBodyTemplate sets a Go template rendered from the task parameters into the request body
+func WithBodyTemplate(text string) Option {
+	return func(t *HTTPTask) {
+		t.templates.body = t.templates.parse("body", text)
+	}
+}
+
+func (rt *requestTemplates) parse(name, text string) *template.Template {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		rt.err = errors.Join(rt.err, fmt.Errorf("invalid %s template: %w", name, err))
+		return nil
+	}
+	return tmpl
+}
+
+// apply renders every configured template and writes the results into the task request fields. The
+// headers of the request are returned as a copy of the task headers, which may be shared with other tasks.
+func (rt *requestTemplates) apply(t *HTTPTask) (httprequest.Headers, error) {
+	if rt.err != nil {
+		return nil, rt.err
+	}
+	if rt.url != nil {
+		url, err := renderTemplate(rt.url, t.Params)
+		if err != nil {
+			return nil, err
+		}
+		t.URL = url
+	}
+	headers := maps.Clone(t.Headers)
+	if headers == nil {
+		headers = make(httprequest.Headers)
+	}
+	for key, tmpl := range rt.headers {
+		value, err := renderTemplate(tmpl, t.Params)
+		if err != nil {
+			return nil, err
+		}
+		headers[key] = value
+	}
+	t.query = nil
+	if len(rt.query) > 0 {
+		t.query = make(url.Values, len(rt.query))
+		for name, tmpl := range rt.query {
+			value, err := renderTemplate(tmpl, t.Params)
+			if err != nil {
+				return nil, err
+			}
+			t.query.Set(name, value)
+		}
+	}
+	if rt.body != nil {
+		body, err := renderTemplate(rt.body, t.Params)
+		if err != nil {
+			return nil, err
+		}
+		t.RequestBody = []byte(body)
+	}
+	return headers, nil
+}
+
+func renderTemplate(tmpl *template.Template, params *Parameters) (string, error) {
+	data := params.Snapshot()
+	for key, value := range data {
+		// Secrets print as a placeholder, templates send their value
+		if s, ok := value.(secret.Secret); ok {
+			data[key] = s.Reveal()
+		}
+	}
+	for _, key := range referencedParams(tmpl) {
+		if _, exists := data[key]; !exists {
+			return "", fmt.Errorf("%s template: %w %q", tmpl.Name(), ErrMissingParam, key)
+		}
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", tmpl.Name(), err)
+	}
+	return sb.String(), nil
+}
+
+// referencedParams lists the top level parameter keys used by the template, such as "baseUrl" in {{.baseUrl}}.
+// Fields inside range and with blocks are skipped since the dot no longer points to the parameters there.
+func referencedParams(tmpl *template.Template) []string {
+	if tmpl.Tree == nil {
+		return nil
+	}
+	var keys []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			keys = append(keys, n.Ident[0])
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.ElseList)
+		}
+	}
+	walk(tmpl.Tree.Root)
+	return keys
+}
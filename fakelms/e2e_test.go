@@ -0,0 +1,139 @@
+package fakelms_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	"github.com/luizhenriquees/go-http-rpa/fakelms"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/report"
+	rpaquiz "github.com/luizhenriquees/go-http-rpa/rpa_quiz"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+	"github.com/luizhenriquees/go-http-rpa/usecase"
+)
+
+const token = "Bearer e2e-token"
+
+// startLMS serves the default seed and returns its URL with the trailing slash of WEBSITE_URL
+func startLMS(t *testing.T, opts ...fakelms.Option) string {
+	t.Helper()
+	lms, err := fakelms.NewServer(fakelms.DefaultSeed(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(lms)
+	t.Cleanup(server.Close)
+	return server.URL + "/"
+}
+
+// newClient returns a client without rate limit nor retries
+func newClient(t *testing.T) *httprequest.Client {
+	t.Helper()
+	httprequest.SetDefaultRetryPolicy(httprequest.RetryPolicy{})
+	client, err := httprequest.NewClient(httprequest.ClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+type score struct {
+	Answers []fakelms.AnswerRecord `json:"answers"`
+	Correct int                    `json:"correct"`
+	Total   int                    `json:"total"`
+}
+
+func fetchScore(t *testing.T, baseURL string) score {
+	t.Helper()
+	resp, err := http.Get(baseURL + "_fake/answers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var s score
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// checkScore compares the answers received by the fake LMS with the corrections recorded in the report
+func checkScore(t *testing.T, baseURL string, run *report.Report, kind string, total int) {
+	t.Helper()
+	got := fetchScore(t, baseURL)
+	if got.Total != total {
+		t.Fatalf("fake LMS received %d answers, want %d: %+v", got.Total, total, got.Answers)
+	}
+	for _, answer := range got.Answers {
+		if answer.Kind != kind {
+			t.Errorf("unexpected %s answer %+v", answer.Kind, answer)
+		}
+	}
+	correct, corrected := run.Score()
+	if corrected != total || correct != got.Correct {
+		t.Errorf("report scored %d/%d, fake LMS %d/%d", correct, corrected, got.Correct, got.Total)
+	}
+}
+
+// setQuizEnv configures the quiz rpa to answer every pending quiz of the fake LMS
+func setQuizEnv(t *testing.T, baseURL, websiteToken string) {
+	t.Setenv("WEBSITE_URL", baseURL)
+	t.Setenv("WEBSITE_TOKEN", websiteToken)
+	t.Setenv("WEBSITE_QUIZES_ID", "")
+	t.Setenv("MAX_PER_EXECUTION", "5")
+	t.Setenv("QUIZ_CONCURRENCY", "2")
+	t.Setenv("QUIZ_MAX_FAILURES", "0")
+}
+
+func TestQuizRpaAgainstFakeLMS(t *testing.T) {
+	baseURL := startLMS(t, fakelms.WithToken(token))
+	setQuizEnv(t, baseURL, token)
+
+	recorder := report.NewRecorder("rpa_quiz")
+	ctx := report.WithRecorder(context.Background(), recorder)
+	rpa := rpaquiz.NewRpaQuiz(rpaquiz.WithClient(newClient(t)))
+	rpa.AddListener(recorder)
+	if err := rpa.Execute(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The two quizzes of the seed hold three questions
+	checkScore(t, baseURL, recorder.Finish(nil), "quiz", 3)
+}
+
+func TestQuizRpaLogsInAgainstFakeLMS(t *testing.T) {
+	baseURL := startLMS(t, fakelms.WithCredentials("rpa", "e2e-password"))
+	setQuizEnv(t, baseURL, "")
+
+	recorder := report.NewRecorder("rpa_quiz")
+	ctx := report.WithRecorder(context.Background(), recorder)
+	login := engine.LoginConfig{URL: baseURL + "api/login", Username: "rpa", Password: secret.New("e2e-password"), TokenPath: "token"}
+	client := newClient(t).WithSession(httprequest.NewSession(""))
+	rpa := rpaquiz.NewRpaQuiz(rpaquiz.WithClient(client), rpaquiz.WithLogin(login))
+	rpa.AddListener(recorder)
+	if err := rpa.Execute(ctx); err != nil {
+		t.Fatal(err)
+	}
+	run := recorder.Finish(nil)
+	if len(run.Steps) == 0 || run.Steps[0].Path != "login" || run.Steps[0].Status != report.StatusSucceeded {
+		t.Errorf("the login is not the first step of the report: %+v", run.Steps)
+	}
+	checkScore(t, baseURL, run, "quiz", 3)
+}
+
+func TestWatchCourseRpaAgainstFakeLMS(t *testing.T) {
+	baseURL := startLMS(t, fakelms.WithToken(token))
+	headers := httprequest.Headers{"Content-Type": "application/json", "X-Authorization": token}
+
+	recorder := report.NewRecorder("watch_course")
+	ctx := report.WithRecorder(context.Background(), recorder)
+	uc := usecase.NewWatchCourseRpa(usecase.WithClient(newClient(t)))
+	if err := uc.Execute(ctx, usecase.CourseInput{BaseUrl: baseURL, Headers: headers}); err != nil {
+		t.Fatal(err)
+	}
+	// The video is watched, the single question test answered and the run stops at the exam
+	checkScore(t, baseURL, recorder.Finish(nil), "test", 1)
+}
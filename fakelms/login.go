@@ -0,0 +1,69 @@
+package fakelms
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"mime"
+	"net/http"
+)
+
+// sessionCookie is the cookie set by the login endpoint, accepted in place of the X-Authorization header
+const sessionCookie = "session"
+
+// WithCredentials enables POST /api/login, which exchanges the username and password for the token, in
+// the "token" field of the response and in a session cookie. Without WithToken the token is random, so the
+// RPAs must log in.
+func WithCredentials(username, password string) Option {
+	return func(s *Server) {
+		s.username, s.password = username, password
+	}
+}
+
+// loginEnabled reports whether the server has credentials
+func (s *Server) loginEnabled() bool {
+	return s.username != "" || s.password != ""
+}
+
+func newToken() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// authorized reports whether the request carries the token, in the X-Authorization header or in the
+// session cookie
+func (s *Server) authorized(r *http.Request) bool {
+	if s.token == "" || r.URL.Path == "/_fake/answers" || r.URL.Path == "/api/login" {
+		return true
+	}
+	if r.Header.Get("X-Authorization") == s.token {
+		return true
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	return err == nil && cookie.Value == s.token
+}
+
+// handleLogin accepts the credentials as a JSON object or as a form, both with username and password fields
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var credentials struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+		credentials.Username, credentials.Password = r.FormValue("username"), r.FormValue("password")
+	} else if err := decodeBody(r, &credentials); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(credentials.Username), []byte(s.username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(credentials.Password), []byte(s.password)) != 1 {
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: s.token, Path: "/", HttpOnly: true})
+	writeJSON(w, http.StatusOK, map[string]string{"token": s.token})
+}
@@ -0,0 +1,109 @@
+package fakelms
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/luizhenriquees/go-http-rpa/entity"
+)
+
+// Seed is the initial content of the fake LMS. The Correct field of every question holds the index of its
+// right option; it is only revealed once the question is answered.
+type Seed struct {
+	Courses []entity.Course `json:"courses"`
+	Quizzes []Quiz          `json:"quizzes"`
+}
+
+// Quiz is a quiz of the fake LMS
+type Quiz struct {
+	ID        int               `json:"id"`
+	Status    string            `json:"status"`
+	Questions []entity.Question `json:"questions"`
+}
+
+// LoadSeed reads the seed from a JSON file
+func LoadSeed(path string) (*Seed, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed: %w", err)
+	}
+	var seed Seed
+	if err := json.Unmarshal(raw, &seed); err != nil {
+		return nil, fmt.Errorf("failed to parse seed %s: %w", path, err)
+	}
+	if err := seed.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid seed %s: %w", path, err)
+	}
+	return &seed, nil
+}
+
+// Validate checks that the ids are unique and that every correct option exists
+func (s *Seed) Validate() error {
+	tasks := make(map[int]bool)
+	for _, course := range s.Courses {
+		for _, module := range course.Modules {
+			for _, task := range module.Tasks {
+				if tasks[task.ID] {
+					return fmt.Errorf("duplicate task %d", task.ID)
+				}
+				tasks[task.ID] = true
+				if err := validateQuestions(task.Questions); err != nil {
+					return fmt.Errorf("task %d: %w", task.ID, err)
+				}
+			}
+		}
+	}
+	quizzes := make(map[int]bool)
+	for _, quiz := range s.Quizzes {
+		if quizzes[quiz.ID] {
+			return fmt.Errorf("duplicate quiz %d", quiz.ID)
+		}
+		quizzes[quiz.ID] = true
+		if err := validateQuestions(quiz.Questions); err != nil {
+			return fmt.Errorf("quiz %d: %w", quiz.ID, err)
+		}
+	}
+	return nil
+}
+
+func validateQuestions(questions []entity.Question) error {
+	for i, question := range questions {
+		if question.Correct < 0 || question.Correct >= len(question.Options) {
+			return fmt.Errorf("question %d: correct option %d out of %d options", i, question.Correct, len(question.Options))
+		}
+	}
+	return nil
+}
+
+// DefaultSeed returns a course with a video, a single question test and an exam, and two pending quizzes
+func DefaultSeed() *Seed {
+	return &Seed{
+		Courses: []entity.Course{{
+			ID:     1,
+			Status: statusPending,
+			Modules: []entity.Module{{
+				ID: 10,
+				Tasks: []entity.Task{
+					{ID: 101, Name: "Introduction", Type: "video", Status: statusPending},
+					{ID: 102, Name: "Check your understanding", Type: "test", Status: statusPending, Questions: []entity.Question{
+						{Question: "Which protocol do the RPAs speak?", Options: []string{"FTP", "HTTP", "SMTP"}, Correct: 1},
+					}},
+					{ID: 103, Name: "Final exam", Type: "exam", Status: statusPending, Questions: []entity.Question{
+						{Question: "Which method starts a task?", Options: []string{"GET", "POST", "DELETE"}, Correct: 1},
+						{Question: "Which header carries the token?", Options: []string{"X-Authorization", "Accept", "Host"}, Correct: 0},
+					}},
+				},
+			}},
+		}},
+		Quizzes: []Quiz{
+			{ID: 1, Status: statusPending, Questions: []entity.Question{
+				{Question: "2 + 2?", Options: []string{"3", "4", "5"}, Correct: 1},
+				{Question: "Capital of France?", Options: []string{"Paris", "Rome", "Madrid"}, Correct: 0},
+			}},
+			{ID: 2, Status: statusPending, Questions: []entity.Question{
+				{Question: "Go is a ... language", Options: []string{"compiled", "markup"}, Correct: 0},
+			}},
+		},
+	}
+}
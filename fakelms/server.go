@@ -0,0 +1,536 @@
+// Package fakelms implements an in-memory stand-in for the LMS the RPAs automate, serving the same API
+// endpoints, so the commands can run locally for demos and integration tests.
+package fakelms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	"github.com/luizhenriquees/go-http-rpa/entity"
+)
+
+const (
+	statusPending  = "pending"
+	statusStarted  = "started"
+	statusFinished = "finished"
+)
+
+// AnswerRecord is an answer received by the fake LMS, with its correctness
+type AnswerRecord struct {
+	// Kind is "quiz" or the type of the task answered, such as "test" or "exam"
+	Kind     string `json:"kind"`
+	ID       int    `json:"id"`
+	Question int    `json:"question"`
+	Option   int    `json:"option"`
+	Correct  bool   `json:"correct"`
+}
+
+// Server is the fake LMS. It is an http.Handler serving the API under the root path, so WEBSITE_URL is
+// the address of the server followed by a slash.
+type Server struct {
+	mu      sync.Mutex
+	courses []entity.Course
+	tasks   map[int]*entity.Task
+	quizzes map[int]*Quiz
+	quizIDs []int
+	answers []AnswerRecord
+	token   string
+	mux     *http.ServeMux
+	logger  engine.Logger
+
+	username string
+	password string
+}
+
+// Option configures the Server
+type Option func(*Server)
+
+// WithToken rejects the requests whose X-Authorization header is not the token
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// NewServer creates a fake LMS holding a copy of the seed
+func NewServer(seed *Seed, opts ...Option) (*Server, error) {
+	if err := seed.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid seed: %w", err)
+	}
+	var state Seed
+	if err := cloneJSON(seed, &state); err != nil {
+		return nil, fmt.Errorf("failed to copy seed: %w", err)
+	}
+	s := &Server{
+		courses: state.Courses,
+		tasks:   make(map[int]*entity.Task),
+		quizzes: make(map[int]*Quiz),
+		logger:  engine.NewLogger("Fake LMS"),
+	}
+	for c := range s.courses {
+		course := &s.courses[c]
+		for m := range course.Modules {
+			module := &course.Modules[m]
+			for t := range module.Tasks {
+				task := &module.Tasks[t]
+				task.CourseID, task.ModuleID = course.ID, module.ID
+				task.QuestionsCount = len(task.Questions)
+				if task.Status == "" {
+					task.Status = statusPending
+				}
+				s.tasks[task.ID] = task
+			}
+		}
+	}
+	for i := range state.Quizzes {
+		quiz := &state.Quizzes[i]
+		if quiz.Status == "" {
+			quiz.Status = statusPending
+		}
+		s.quizzes[quiz.ID] = quiz
+		s.quizIDs = append(s.quizIDs, quiz.ID)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.loginEnabled() && s.token == "" {
+		token, err := newToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		s.token = token
+	}
+
+	s.mux = http.NewServeMux()
+	if s.loginEnabled() {
+		s.mux.HandleFunc("POST /api/login", s.handleLogin)
+	}
+	s.mux.HandleFunc("GET /api/status", s.handleStatus)
+	s.mux.HandleFunc("GET /api/task/{id}", s.handleGetTask)
+	s.mux.HandleFunc("POST /api/task/{id}/start", s.handleStartTask)
+	s.mux.HandleFunc("POST /api/task/{id}/answer", s.handleAnswerTask)
+	s.mux.HandleFunc("POST /api/task/{id}/finish", s.handleFinishTask)
+	s.mux.HandleFunc("GET /api/quiz", s.handleListQuizzes)
+	s.mux.HandleFunc("POST /api/quiz/{id}/start", s.handleStartQuiz)
+	s.mux.HandleFunc("POST /api/quiz/{id}/answer", s.handleAnswerQuiz)
+	s.mux.HandleFunc("GET /_fake/answers", s.handleAnswers)
+	return s, nil
+}
+
+// ServeHTTP checks the token, or the session cookie, and routes the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("%s %s", r.Method, r.URL.Path)
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Answers returns the answers received so far
+func (s *Server) Answers() []AnswerRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AnswerRecord(nil), s.answers...)
+}
+
+// Score returns how many of the received answers were right, and how many answers were received
+func (s *Server) Score() (correct, total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, answer := range s.answers {
+		if answer.Correct {
+			correct++
+		}
+	}
+	return correct, len(s.answers)
+}
+
+type questionView struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+	Correct  *int     `json:"correct,omitempty"`
+	Answered *int     `json:"answered"`
+}
+
+type taskView struct {
+	ID             int            `json:"id"`
+	Name           string         `json:"name"`
+	CourseID       int            `json:"course_id"`
+	ModuleID       int            `json:"module_id"`
+	Type           string         `json:"type"`
+	Status         string         `json:"status"`
+	QuestionsCount int            `json:"questions_count,omitempty"`
+	Questions      []questionView `json:"questions"`
+}
+
+type moduleView struct {
+	ID                int        `json:"id"`
+	TaskCount         int        `json:"tasks_count"`
+	FinishedTaskCount int        `json:"finished_tasks_count"`
+	Tasks             []taskView `json:"tasks"`
+}
+
+type courseView struct {
+	ID        int          `json:"id"`
+	TaskCount int          `json:"tasks_count"`
+	TaskDone  int          `json:"tasks_done"`
+	Status    string       `json:"status"`
+	Modules   []moduleView `json:"modules"`
+}
+
+type quizView struct {
+	ID             int            `json:"id"`
+	QuestionsCount int            `json:"questions_count"`
+	Status         string         `json:"status"`
+	Questions      []questionView `json:"questions,omitempty"`
+}
+
+// viewQuestions hides the correct option of the questions, unless reveal is set or the question was answered
+// and revealAnswered is set
+func viewQuestions(questions []entity.Question, reveal, revealAnswered bool) []questionView {
+	views := make([]questionView, len(questions))
+	for i, question := range questions {
+		views[i] = questionView{Question: question.Question, Options: question.Options, Answered: question.Answered}
+		if reveal || (revealAnswered && question.Answered != nil) {
+			correct := question.Correct
+			views[i].Correct = &correct
+		}
+	}
+	return views
+}
+
+func viewTask(task *entity.Task) taskView {
+	return taskView{
+		ID:             task.ID,
+		Name:           task.Name,
+		CourseID:       task.CourseID,
+		ModuleID:       task.ModuleID,
+		Type:           task.Type,
+		Status:         task.Status,
+		QuestionsCount: task.QuestionsCount,
+		Questions:      viewQuestions(task.Questions, task.Status == statusFinished, false),
+	}
+}
+
+func viewQuiz(quiz *Quiz, withQuestions bool) quizView {
+	view := quizView{ID: quiz.ID, QuestionsCount: len(quiz.Questions), Status: quiz.Status}
+	if withQuestions {
+		view.Questions = viewQuestions(quiz.Questions, false, true)
+	}
+	return view
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	courses := make([]courseView, 0, len(s.courses))
+	for _, course := range s.courses {
+		view := courseView{ID: course.ID, Status: course.Status}
+		for _, module := range course.Modules {
+			moduleStatus := moduleView{ID: module.ID, TaskCount: len(module.Tasks)}
+			for i := range module.Tasks {
+				task := &module.Tasks[i]
+				if task.Status == statusFinished {
+					moduleStatus.FinishedTaskCount++
+				}
+				moduleStatus.Tasks = append(moduleStatus.Tasks, viewTask(task))
+			}
+			view.TaskCount += moduleStatus.TaskCount
+			view.TaskDone += moduleStatus.FinishedTaskCount
+			view.Modules = append(view.Modules, moduleStatus)
+		}
+		courses = append(courses, view)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"courses": courses})
+}
+
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, err := s.task(r)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, viewTask(task))
+}
+
+func (s *Server) handleStartTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, err := s.task(r)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if task.Status == statusFinished {
+		writeError(w, http.StatusConflict, fmt.Sprintf("task %d is already finished", task.ID))
+		return
+	}
+	task.Status = statusStarted
+	s.logger.Info("Task %d started", task.ID)
+	writeJSON(w, http.StatusOK, viewTask(task))
+}
+
+type taskAnswers struct {
+	Answers []int `json:"answers"`
+}
+
+func (s *Server) handleAnswerTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, err := s.task(r)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if task.Status != statusStarted {
+		writeError(w, http.StatusConflict, fmt.Sprintf("task %d is not started", task.ID))
+		return
+	}
+	var payload taskAnswers
+	if err := decodeBody(r, &payload); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if err := setTaskAnswers(task, payload.Answers); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, viewTask(task))
+}
+
+// handleFinishTask finishes the task, recording the answers sent with it or through the answer endpoint,
+// and answers the task with its correction
+func (s *Server) handleFinishTask(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, err := s.task(r)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if task.Status != statusStarted {
+		writeError(w, http.StatusConflict, fmt.Sprintf("task %d is not started", task.ID))
+		return
+	}
+	var payload taskAnswers
+	if err := decodeBody(r, &payload); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if len(payload.Answers) > 0 {
+		if err := setTaskAnswers(task, payload.Answers); err != nil {
+			writeFailure(w, err)
+			return
+		}
+	}
+	for i, question := range task.Questions {
+		if question.Answered != nil {
+			s.record(AnswerRecord{Kind: task.Type, ID: task.ID, Question: i, Option: *question.Answered, Correct: *question.Answered == question.Correct})
+		}
+	}
+	task.Status = statusFinished
+	s.updateCourseStatus(task.CourseID)
+	s.logger.Info("Task %d finished", task.ID)
+	writeJSON(w, http.StatusOK, viewTask(task))
+}
+
+func setTaskAnswers(task *entity.Task, answers []int) error {
+	if len(answers) != len(task.Questions) {
+		return requestError{http.StatusBadRequest, fmt.Sprintf("task %d has %d questions, got %d answers", task.ID, len(task.Questions), len(answers))}
+	}
+	for i, option := range answers {
+		if option < 0 || option >= len(task.Questions[i].Options) {
+			return requestError{http.StatusBadRequest, fmt.Sprintf("question %d has no option %d", i, option)}
+		}
+		task.Questions[i].Answered = &answers[i]
+	}
+	return nil
+}
+
+func (s *Server) updateCourseStatus(courseID int) {
+	for c := range s.courses {
+		course := &s.courses[c]
+		if course.ID != courseID {
+			continue
+		}
+		course.Status = statusFinished
+		for _, module := range course.Modules {
+			for _, task := range module.Tasks {
+				if task.Status != statusFinished {
+					course.Status = statusStarted
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) handleListQuizzes(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quizzes := make([]quizView, 0, len(s.quizIDs))
+	for _, id := range s.quizIDs {
+		quizzes = append(quizzes, viewQuiz(s.quizzes[id], false))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"quiz": quizzes})
+}
+
+func (s *Server) handleStartQuiz(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quiz, err := s.quiz(r)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if quiz.Status == statusFinished {
+		writeError(w, http.StatusConflict, fmt.Sprintf("quiz %d is already finished", quiz.ID))
+		return
+	}
+	quiz.Status = statusStarted
+	s.logger.Info("Quiz %d started", quiz.ID)
+	writeJSON(w, http.StatusOK, viewQuiz(quiz, true))
+}
+
+// handleAnswerQuiz answers a single question and returns the quiz with the correction of the answered
+// questions. The quiz is finished once every question is answered.
+func (s *Server) handleAnswerQuiz(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quiz, err := s.quiz(r)
+	if err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if quiz.Status != statusStarted {
+		writeError(w, http.StatusConflict, fmt.Sprintf("quiz %d is not started", quiz.ID))
+		return
+	}
+	var payload struct {
+		Answer        *int `json:"answer"`
+		QuestionIndex int  `json:"question_index"`
+	}
+	if err := decodeBody(r, &payload); err != nil {
+		writeFailure(w, err)
+		return
+	}
+	if payload.QuestionIndex < 0 || payload.QuestionIndex >= len(quiz.Questions) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("quiz %d has no question %d", quiz.ID, payload.QuestionIndex))
+		return
+	}
+	question := &quiz.Questions[payload.QuestionIndex]
+	if payload.Answer == nil || *payload.Answer < 0 || *payload.Answer >= len(question.Options) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid answer for question %d", payload.QuestionIndex))
+		return
+	}
+	if question.Answered != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("question %d is already answered", payload.QuestionIndex))
+		return
+	}
+	question.Answered = payload.Answer
+	s.record(AnswerRecord{Kind: "quiz", ID: quiz.ID, Question: payload.QuestionIndex, Option: *payload.Answer, Correct: *payload.Answer == question.Correct})
+
+	quiz.Status = statusFinished
+	for _, question := range quiz.Questions {
+		if question.Answered == nil {
+			quiz.Status = statusStarted
+		}
+	}
+	writeJSON(w, http.StatusOK, viewQuiz(quiz, true))
+}
+
+func (s *Server) handleAnswers(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	correct := 0
+	for _, answer := range s.answers {
+		if answer.Correct {
+			correct++
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"answers": s.answers, "correct": correct, "total": len(s.answers)})
+}
+
+// record keeps the answer, the caller holds the lock
+func (s *Server) record(answer AnswerRecord) {
+	s.answers = append(s.answers, answer)
+	s.logger.Info("%s %d question %d answered with option %d, correct: %t", answer.Kind, answer.ID, answer.Question, answer.Option, answer.Correct)
+}
+
+func (s *Server) task(r *http.Request) (*entity.Task, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil, requestError{http.StatusBadRequest, "invalid task id"}
+	}
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, requestError{http.StatusNotFound, fmt.Sprintf("task %d not found", id)}
+	}
+	return task, nil
+}
+
+func (s *Server) quiz(r *http.Request) (*Quiz, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return nil, requestError{http.StatusBadRequest, "invalid quiz id"}
+	}
+	quiz, ok := s.quizzes[id]
+	if !ok {
+		return nil, requestError{http.StatusNotFound, fmt.Sprintf("quiz %d not found", id)}
+	}
+	return quiz, nil
+}
+
+// requestError is a failure reported to the client with its status code
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e requestError) Error() string {
+	return e.message
+}
+
+// decodeBody decodes the JSON body into v, leaving v untouched when the body is empty
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return requestError{http.StatusBadRequest, "invalid JSON body: " + err.Error()}
+	}
+	return nil
+}
+
+func writeFailure(w http.ResponseWriter, err error) {
+	var reqErr requestError
+	if errors.As(err, &reqErr) {
+		writeError(w, reqErr.status, reqErr.message)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// writeError answers with the message in the "message" field, read by httprequest.HTTPError
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func cloneJSON(src, dst any) error {
+	raw, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dst)
+}
@@ -2,4 +2,7 @@ module github.com/luizhenriquees/go-http-rpa
 
 go 1.22
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/joho/godotenv v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
+)
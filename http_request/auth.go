@@ -0,0 +1,272 @@
+package httprequest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// Grant types supported by OAuthProvider
+const (
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// AuthProvider obtains a new token once the website rejected the current one
+type AuthProvider interface {
+	// Refresh returns the new value of the token header
+	Refresh(ctx context.Context) (string, error)
+}
+
+// AuthProviderFunc adapts a function to the AuthProvider interface
+type AuthProviderFunc func(ctx context.Context) (string, error)
+
+func (f AuthProviderFunc) Refresh(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// WithAuth returns a client refreshing its token with the provider when a response of one of the hosts is
+// 401 Unauthorized or 403 Forbidden, then sending the rejected request once more with the new token. Without
+// hosts, only the hosts the session holds a token for are refreshed. The token is stored in the session of
+// c for the host of the request, a new session being created when c has none, so every later request to
+// that host carries it. The requests to the other hosts pass through unchanged.
+func (c *Client) WithAuth(provider AuthProvider, hosts ...string) *Client {
+	c = c.orDefault()
+	if c.session == nil {
+		c = c.WithSession(NewSession(""))
+	}
+	auth := &authRefresher{provider: provider, session: c.session}
+	if len(hosts) > 0 {
+		return c.With(ForHosts(auth.middleware(), hosts...))
+	}
+	return c.With(auth.sessionHostsOnly(auth.middleware()))
+}
+
+// authRefresher refreshes the tokens of a session, one refresh at a time
+type authRefresher struct {
+	provider AuthProvider
+	session  *Session
+	mu       sync.Mutex
+}
+
+type refreshingKey struct{}
+
+// sessionHostsOnly applies the middleware only to the requests sent to a host the session holds a token for
+func (a *authRefresher) sessionHostsOnly(middleware Middleware) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		wrapped := middleware(next)
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if _, ok := a.session.Token(req.URL.Host); ok {
+				return wrapped.RoundTrip(req)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func (a *authRefresher) middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// The requests sent by the provider itself are not refreshed
+			if req.Context().Value(refreshingKey{}) != nil {
+				return next.RoundTrip(req)
+			}
+			rejected, ok := a.session.Token(req.URL.Host)
+			if !ok {
+				rejected = req.Header.Get(a.session.TokenHeader())
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+				return resp, err
+			}
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, nil
+			}
+
+			if err := a.refresh(req.Context(), req.URL.Host, rejected); err != nil {
+				slog.WarnContext(req.Context(), "Could not refresh the token", "host", req.URL.Host, "status", resp.StatusCode, "error", err)
+				return resp, nil
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+
+			replay := req.Clone(req.Context())
+			if req.GetBody != nil {
+				if replay.Body, err = req.GetBody(); err != nil {
+					return nil, err
+				}
+			}
+			return next.RoundTrip(replay)
+		})
+	}
+}
+
+// refresh stores a new token for the host, unless the rejected token was already replaced by a
+// concurrent request
+func (a *authRefresher) refresh(ctx context.Context, host, rejected string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if current, ok := a.session.Token(host); ok && current != rejected {
+		return nil
+	}
+	token, err := a.provider.Refresh(context.WithValue(ctx, refreshingKey{}, true))
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errors.New("the auth provider returned an empty token")
+	}
+	a.session.SetToken(host, token)
+	slog.InfoContext(ctx, "Token refreshed", "host", host)
+	return nil
+}
+
+// OAuthConfig describes an OAuth 2 token endpoint
+type OAuthConfig struct {
+	TokenURL string
+	// Grant is GrantRefreshToken or GrantClientCredentials
+	Grant        string
+	ClientID     string
+	ClientSecret secret.Secret
+	// RefreshToken is the initial refresh token of the refresh_token grant. It is replaced by the refresh
+	// token of every response holding one.
+	RefreshToken secret.Secret
+	Scope        string
+	// TokenPrefix is prepended to the access token, such as "Bearer "
+	TokenPrefix string
+	// Hosts are the hosts whose tokens are refreshed, such as "lms.example.com", see Client.WithAuth
+	Hosts []string
+}
+
+// OAuthConfigFromEnv reads the token endpoint from the AUTH_TOKEN_URL, AUTH_GRANT_TYPE, AUTH_CLIENT_ID,
+// AUTH_CLIENT_SECRET, AUTH_REFRESH_TOKEN, AUTH_SCOPE, AUTH_TOKEN_PREFIX and AUTH_HOSTS (comma separated)
+// environment variables. The grant defaults to refresh_token, the prefix to "Bearer " and the hosts to the
+// host of WEBSITE_URL. It returns nil when AUTH_TOKEN_URL is not set.
+func OAuthConfigFromEnv() (*OAuthConfig, error) {
+	tokenURL := os.Getenv("AUTH_TOKEN_URL")
+	if tokenURL == "" {
+		return nil, nil
+	}
+	cfg := &OAuthConfig{
+		TokenURL:     tokenURL,
+		Grant:        os.Getenv("AUTH_GRANT_TYPE"),
+		ClientID:     os.Getenv("AUTH_CLIENT_ID"),
+		ClientSecret: secret.New(os.Getenv("AUTH_CLIENT_SECRET")),
+		RefreshToken: secret.New(os.Getenv("AUTH_REFRESH_TOKEN")),
+		Scope:        os.Getenv("AUTH_SCOPE"),
+		TokenPrefix:  "Bearer ",
+	}
+	if cfg.Grant == "" {
+		cfg.Grant = GrantRefreshToken
+	}
+	if prefix, ok := os.LookupEnv("AUTH_TOKEN_PREFIX"); ok {
+		cfg.TokenPrefix = prefix
+	}
+	for _, host := range strings.Split(os.Getenv("AUTH_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.Hosts = append(cfg.Hosts, host)
+		}
+	}
+	if len(cfg.Hosts) == 0 && os.Getenv("WEBSITE_URL") != "" {
+		websiteURL, err := url.Parse(os.Getenv("WEBSITE_URL"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBSITE_URL: %w", err)
+		}
+		cfg.Hosts = []string{websiteURL.Host}
+	}
+	return cfg, nil
+}
+
+// OAuthProvider refreshes the token with the refresh_token or the client_credentials grant. It is safe
+// for concurrent use.
+type OAuthProvider struct {
+	cfg    OAuthConfig
+	client *Client
+
+	mu           sync.Mutex
+	refreshToken secret.Secret
+}
+
+// NewOAuthProvider creates a provider requesting the tokens with the client, nil meaning the default
+// client. The token requests are never refreshed themselves.
+func NewOAuthProvider(cfg OAuthConfig, client *Client) (*OAuthProvider, error) {
+	if cfg.TokenURL == "" {
+		return nil, errors.New("missing token URL")
+	}
+	switch cfg.Grant {
+	case GrantRefreshToken:
+		if cfg.RefreshToken.IsZero() {
+			return nil, errors.New("the refresh_token grant needs a refresh token")
+		}
+	case GrantClientCredentials:
+		if cfg.ClientID == "" {
+			return nil, errors.New("the client_credentials grant needs a client id")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported grant type %q: expected %s or %s", cfg.Grant, GrantRefreshToken, GrantClientCredentials)
+	}
+	return &OAuthProvider{cfg: cfg, client: client, refreshToken: cfg.RefreshToken}, nil
+}
+
+// tokenResponse is the successful response of an OAuth 2 token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh requests a new access token. It is sent through the cassette of ctx, if any, but never faked by
+// a dry run.
+func (p *OAuthProvider) Refresh(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	form := url.Values{"grant_type": {p.cfg.Grant}}
+	if p.cfg.Grant == GrantRefreshToken {
+		form.Set("refresh_token", p.refreshToken.Reveal())
+	}
+	if p.cfg.ClientID != "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	if !p.cfg.ClientSecret.IsZero() {
+		form.Set("client_secret", p.cfg.ClientSecret.Reveal())
+	}
+	if p.cfg.Scope != "" {
+		form.Set("scope", p.cfg.Scope)
+	}
+	body := FormBody(form)
+
+	client := p.client.orDefault()
+	resp, err := doWithRetry(ctx, client.clientFor(ctx), client.limiterFor(ctx), GetDefaultRetryPolicy(), func() (*http.Request, error) {
+		return prepareRequest(ctx, POST, p.cfg.TokenURL, Headers{"Content-Type": body.ContentType, "Accept": "application/json"}, body.Data)
+	})
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := CheckStatus(resp); err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token response: %w", err)
+	}
+	if strings.TrimSpace(token.AccessToken) == "" {
+		return "", errors.New("invalid token response: missing access_token")
+	}
+	if token.RefreshToken != "" && token.RefreshToken != p.refreshToken.Reveal() {
+		secret.Unregister(p.refreshToken.Reveal())
+		p.refreshToken = secret.New(token.RefreshToken)
+	}
+	accessToken := p.cfg.TokenPrefix + token.AccessToken
+	secret.Register(accessToken)
+	return accessToken, nil
+}
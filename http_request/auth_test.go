@@ -0,0 +1,169 @@
+package httprequest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+)
+
+// tokenServer rejects the requests whose X-Authorization header is not the valid token, recording the
+// bodies of the accepted ones
+type tokenServer struct {
+	*httptest.Server
+	valid    string
+	requests atomic.Int32
+	mu       sync.Mutex
+	bodies   []string
+}
+
+func newTokenServer(t *testing.T, valid string) *tokenServer {
+	t.Helper()
+	s := &tokenServer{valid: valid}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if r.Header.Get("X-Authorization") != s.valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *tokenServer) host(t *testing.T) string {
+	t.Helper()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
+
+// countingProvider returns the token after a pause, counting the refreshes
+func countingProvider(token string, refreshes *atomic.Int32) httprequest.AuthProvider {
+	return httprequest.AuthProviderFunc(func(ctx context.Context) (string, error) {
+		refreshes.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return token, nil
+	})
+}
+
+// sessionClient returns a client whose session holds the token for the host of the server
+func sessionClient(t *testing.T, server *tokenServer, token string) (*httprequest.Client, *httprequest.Session) {
+	t.Helper()
+	session := httprequest.NewSession("")
+	session.SetToken(server.host(t), token)
+	return newClient(t).WithSession(session), session
+}
+
+func TestAuthRefreshesAndReplaysTheRejectedRequest(t *testing.T) {
+	server := newTokenServer(t, "new")
+	client, session := sessionClient(t, server, "expired")
+	var refreshes atomic.Int32
+	client = client.WithAuth(countingProvider("new", &refreshes))
+
+	resp, err := client.Do(context.Background(), httprequest.POST, server.URL, nil, []byte(`{"answer":1}`), httprequest.RetryPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || refreshes.Load() != 1 || server.requests.Load() != 2 {
+		t.Errorf("got %d after %d refreshes and %d requests, want 200 after 1 and 2", resp.StatusCode, refreshes.Load(), server.requests.Load())
+	}
+	if len(server.bodies) != 1 || server.bodies[0] != `{"answer":1}` {
+		t.Errorf("the replayed request carried %q", server.bodies)
+	}
+	if token, _ := session.Token(server.host(t)); token != "new" {
+		t.Errorf("the session holds %q, want the new token", token)
+	}
+}
+
+func TestAuthRefreshesOnceForConcurrentRejections(t *testing.T) {
+	server := newTokenServer(t, "new")
+	client, _ := sessionClient(t, server, "expired")
+	var refreshes atomic.Int32
+	client = client.WithAuth(countingProvider("new", &refreshes))
+
+	var wg sync.WaitGroup
+	statuses := make([]int, 8)
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Get(context.Background(), server.URL, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+	for _, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("statuses = %v, want every request to succeed", statuses)
+			break
+		}
+	}
+	if refreshes.Load() != 1 {
+		t.Errorf("refreshed %d times, want once", refreshes.Load())
+	}
+}
+
+func TestAuthRefreshesOnlyTheConfiguredHosts(t *testing.T) {
+	tests := []struct {
+		name          string
+		sessionToken  bool
+		hosts         func(server *tokenServer) []string
+		wantRefreshes int32
+	}{
+		{name: "host of a session token", sessionToken: true, wantRefreshes: 1},
+		{name: "host without a session token", wantRefreshes: 0},
+		{name: "listed host", hosts: func(s *tokenServer) []string { return []string{s.host(t)} }, wantRefreshes: 1},
+		{name: "other host listed", sessionToken: true, hosts: func(*tokenServer) []string { return []string{"api.openai.com"} }, wantRefreshes: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTokenServer(t, "new")
+			client := newClient(t)
+			if tt.sessionToken {
+				client, _ = sessionClient(t, server, "expired")
+			}
+			var hosts []string
+			if tt.hosts != nil {
+				hosts = tt.hosts(server)
+			}
+			var refreshes atomic.Int32
+			client = client.WithAuth(countingProvider("new", &refreshes), hosts...)
+
+			resp, err := client.Get(context.Background(), server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if refreshes.Load() != tt.wantRefreshes {
+				t.Errorf("refreshed %d times, want %d", refreshes.Load(), tt.wantRefreshes)
+			}
+			wantStatus := http.StatusUnauthorized
+			if tt.wantRefreshes > 0 {
+				wantStatus = http.StatusOK
+			}
+			if resp.StatusCode != wantStatus {
+				t.Errorf("got %d, want %d", resp.StatusCode, wantStatus)
+			}
+		})
+	}
+}
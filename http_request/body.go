@@ -0,0 +1,103 @@
+package httprequest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Body is an encoded request body with its content type
+type Body struct {
+	ContentType string
+	Data        []byte
+}
+
+// JSONBody marshals the value as a JSON body
+func JSONBody(value any) (Body, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Body{}, fmt.Errorf("failed to encode JSON body: %w", err)
+	}
+	return Body{ContentType: "application/json", Data: data}, nil
+}
+
+// FormBody encodes the values as an application/x-www-form-urlencoded body
+func FormBody(values url.Values) Body {
+	return Body{ContentType: "application/x-www-form-urlencoded", Data: []byte(values.Encode())}
+}
+
+// MultipartFile is a file part of a multipart body
+type MultipartFile struct {
+	Field       string
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// MultipartBody encodes the fields, in key order, and the files as a multipart/form-data body
+func MultipartBody(fields url.Values, files ...MultipartFile) (Body, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range fields[key] {
+			if err := writer.WriteField(key, value); err != nil {
+				return Body{}, fmt.Errorf("failed to encode multipart field %q: %w", key, err)
+			}
+		}
+	}
+	for _, file := range files {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(file.Field), escapeQuotes(file.Filename)))
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return Body{}, fmt.Errorf("failed to encode multipart file %q: %w", file.Field, err)
+		}
+		if _, err := part.Write(file.Content); err != nil {
+			return Body{}, fmt.Errorf("failed to encode multipart file %q: %w", file.Field, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return Body{}, fmt.Errorf("failed to encode multipart body: %w", err)
+	}
+	return Body{ContentType: writer.FormDataContentType(), Data: buf.Bytes()}, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// AddQuery adds the query parameters to the URL, keeping the parameters it already has
+func AddQuery(rawURL string, query url.Values) (string, error) {
+	if len(query) == 0 {
+		return rawURL, nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	values := parsed.Query()
+	for key, list := range query {
+		for _, value := range list {
+			values.Add(key, value)
+		}
+	}
+	parsed.RawQuery = values.Encode()
+	return parsed.String(), nil
+}
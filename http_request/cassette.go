@@ -0,0 +1,349 @@
+package httprequest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// ErrInteractionNotFound is returned when a replayed cassette holds no interaction matching a request
+var ErrInteractionNotFound = errors.New("no matching interaction in cassette")
+
+type cassetteContextKey struct{}
+
+// CassetteMode tells whether a cassette captures the requests of the run or answers them
+type CassetteMode string
+
+const (
+	// ModeRecord sends the requests and captures every request and response into the cassette
+	ModeRecord CassetteMode = "record"
+	// ModeReplay answers the requests with the recorded responses, without sending them
+	ModeReplay CassetteMode = "replay"
+)
+
+// Match selects the parts of a request compared to find its recorded interaction
+type Match uint8
+
+const (
+	// MatchMethod compares the HTTP method
+	MatchMethod Match = 1 << iota
+	// MatchURL compares the full URL
+	MatchURL
+	// MatchPath compares the URL path and query, ignoring the scheme and host so a cassette can be
+	// replayed against another base URL
+	MatchPath
+	// MatchBody compares the request body
+	MatchBody
+)
+
+// DefaultMatch compares the method and the URL path of the requests
+const DefaultMatch = MatchMethod | MatchPath
+
+// ParseMatch reads a comma separated list of "method", "url", "path" and "body"
+func ParseMatch(value string) (Match, error) {
+	var match Match
+	for _, part := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "method":
+			match |= MatchMethod
+		case "url":
+			match |= MatchURL
+		case "path":
+			match |= MatchPath
+		case "body":
+			match |= MatchBody
+		case "":
+		default:
+			return 0, fmt.Errorf("unknown cassette match %q", part)
+		}
+	}
+	return match, nil
+}
+
+// Interaction is a request and the response it got
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request kept in a cassette. Request headers are not recorded, since
+// they carry the credentials of the run.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response kept in a cassette
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Cassette records the HTTP interactions of a run to a file and replays them, so a run can be reproduced
+// without the website. Replayed interactions are consumed in order: identical requests get the
+// responses recorded for them one after the other. It is safe for concurrent use.
+type Cassette struct {
+	path         string
+	mode         CassetteMode
+	match        Match
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewCassette creates an empty cassette recording to path
+func NewCassette(path string) *Cassette {
+	return &Cassette{path: path, mode: ModeRecord, match: DefaultMatch}
+}
+
+// LoadCassette reads the cassette at path to replay it, matching requests on the given parts
+func LoadCassette(path string, match Match) (*Cassette, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(raw, &interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &Cassette{
+		path:         path,
+		mode:         ModeReplay,
+		match:        match,
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+	}, nil
+}
+
+// CassetteFromEnv opens the cassette configured by the HTTP_CASSETTE (file), HTTP_CASSETTE_MODE ("record"
+// or "replay", the default) and HTTP_CASSETTE_MATCH (see ParseMatch, "method,path" by default)
+// environment variables. It returns nil when HTTP_CASSETTE is not set.
+func CassetteFromEnv() (*Cassette, error) {
+	path := os.Getenv("HTTP_CASSETTE")
+	if path == "" {
+		return nil, nil
+	}
+	match := DefaultMatch
+	if value := os.Getenv("HTTP_CASSETTE_MATCH"); value != "" {
+		var err error
+		if match, err = ParseMatch(value); err != nil {
+			return nil, fmt.Errorf("invalid HTTP_CASSETTE_MATCH: %w", err)
+		}
+	}
+	switch mode := CassetteMode(os.Getenv("HTTP_CASSETTE_MODE")); mode {
+	case ModeRecord:
+		return NewCassette(path), nil
+	case ModeReplay, "":
+		return LoadCassette(path, match)
+	default:
+		return nil, fmt.Errorf("invalid HTTP_CASSETTE_MODE %q", mode)
+	}
+}
+
+// Mode returns whether the cassette records or replays
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Interactions returns how many interactions the cassette holds
+func (c *Cassette) Interactions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions)
+}
+
+// WithCassette returns a context where the requests sent by a Client are recorded to or replayed from
+// the cassette
+func WithCassette(ctx context.Context, cassette *Cassette) context.Context {
+	return context.WithValue(ctx, cassetteContextKey{}, cassette)
+}
+
+func cassetteFrom(ctx context.Context) *Cassette {
+	cassette, _ := ctx.Value(cassetteContextKey{}).(*Cassette)
+	return cassette
+}
+
+// Middleware returns the transport middleware recording the requests sent through next, or replaying
+// them without calling next, depending on the mode of the cassette
+func (c *Cassette) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, req, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			if c.mode == ModeReplay {
+				return c.replay(req, body)
+			}
+			return c.record(next, req, body)
+		})
+	}
+}
+
+func (c *Cassette) record(next http.RoundTripper, req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: RecordedRequest{Method: req.Method, URL: req.URL.String(), Body: string(body)},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Headers:    resp.Header.Clone(),
+			Body:       string(respBody),
+		},
+	})
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request, body []byte) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, interaction := range c.interactions {
+		if c.used[i] || !c.matches(interaction.Request, req, body) {
+			continue
+		}
+		c.used[i] = true
+		recorded := interaction.Response
+		return &http.Response{
+			Status:        recorded.Status,
+			StatusCode:    recorded.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        recorded.Headers.Clone(),
+			Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+}
+
+// matches compares the parts of the request selected by the cassette match
+func (c *Cassette) matches(recorded RecordedRequest, req *http.Request, body []byte) bool {
+	if c.match&MatchMethod != 0 && !strings.EqualFold(recorded.Method, req.Method) {
+		return false
+	}
+	requestURL := secret.Redact(req.URL.String())
+	if c.match&MatchURL != 0 && recorded.URL != requestURL {
+		return false
+	}
+	if c.match&MatchPath != 0 {
+		recordedURL, err := url.Parse(recorded.URL)
+		if err != nil {
+			return false
+		}
+		liveURL, err := url.Parse(requestURL)
+		if err != nil || recordedURL.Path != liveURL.Path || recordedURL.RawQuery != liveURL.RawQuery {
+			return false
+		}
+	}
+	if c.match&MatchBody != 0 && recorded.Body != secret.Redact(string(body)) {
+		return false
+	}
+	return true
+}
+
+// Save writes the recorded interactions to the cassette file, with their secrets redacted, see
+// secret.Redact. Replayed cassettes are left untouched.
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return nil
+	}
+	c.mu.Lock()
+	interactions := make([]Interaction, len(c.interactions))
+	for i, interaction := range c.interactions {
+		interactions[i] = interaction.redacted()
+	}
+	c.mu.Unlock()
+	raw, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if dir := filepath.Dir(c.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(c.path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// redacted returns a copy of the interaction without its secrets. The replayed requests are redacted
+// the same way before being compared with it.
+func (i Interaction) redacted() Interaction {
+	i.Request.URL = secret.Redact(i.Request.URL)
+	i.Request.Body = secret.Redact(i.Request.Body)
+	headers := make(http.Header, len(i.Response.Headers))
+	for key, values := range i.Response.Headers {
+		redacted := make([]string, len(values))
+		for j, value := range values {
+			if secret.IsSensitive(key) {
+				value = secret.Redacted
+			}
+			redacted[j] = secret.Redact(value)
+		}
+		headers[key] = redacted
+	}
+	i.Response.Headers = headers
+	i.Response.Body = secret.Redact(i.Response.Body)
+	return i
+}
+
+// readRequestBody reads the request body without altering the caller's request and returns the request to
+// send. The body is read from GetBody when set, otherwise from a clone of the request given the read copy.
+func readRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		source, err := req.GetBody()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		body, err := io.ReadAll(source)
+		_ = source.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		return body, req, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, clone, nil
+}
@@ -0,0 +1,167 @@
+package httprequest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+)
+
+// echoServer answers every request with its method, path and body
+func echoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func send(t *testing.T, ctx context.Context, client *httprequest.Client, method httprequest.HTTPMethod, url, body string) (string, error) {
+	t.Helper()
+	resp, err := client.Do(ctx, method, url, nil, []byte(body), httprequest.RetryPolicy{})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), nil
+}
+
+// recordCassette records the requests to a cassette file and returns its path
+func recordCassette(t *testing.T, requests [][3]string) string {
+	t.Helper()
+	server := echoServer(t)
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	cassette := httprequest.NewCassette(path)
+	ctx := httprequest.WithCassette(context.Background(), cassette)
+	for _, request := range requests {
+		if _, err := send(t, ctx, newClient(t), httprequest.HTTPMethod(request[0]), server.URL+request[1], request[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cassette.Interactions() != len(requests) {
+		t.Fatalf("recorded %d interactions, want %d", cassette.Interactions(), len(requests))
+	}
+	if err := cassette.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCassetteReplay(t *testing.T) {
+	path := recordCassette(t, [][3]string{
+		{"GET", "/api/quiz?page=1", ""},
+		{"POST", "/api/quiz/1/answer", `{"answer":1}`},
+		{"POST", "/api/quiz/1/answer", `{"answer":2}`},
+	})
+	// The website is gone and the replay targets another base URL
+	const otherURL = "http://replay.invalid"
+
+	tests := []struct {
+		name   string
+		match  httprequest.Match
+		method httprequest.HTTPMethod
+		path   string
+		body   string
+		want   string
+	}{
+		{name: "method and path", match: httprequest.DefaultMatch, method: "GET", path: "/api/quiz?page=1", want: "GET /api/quiz?page=1 "},
+		{name: "query compared", match: httprequest.DefaultMatch, method: "GET", path: "/api/quiz?page=2"},
+		{name: "method compared", match: httprequest.DefaultMatch, method: "PUT", path: "/api/quiz?page=1"},
+		{name: "consumed in order", match: httprequest.DefaultMatch, method: "POST", path: "/api/quiz/1/answer", body: `{"answer":2}`, want: `POST /api/quiz/1/answer {"answer":1}`},
+		{name: "body compared", match: httprequest.DefaultMatch | httprequest.MatchBody, method: "POST", path: "/api/quiz/1/answer", body: `{"answer":2}`, want: `POST /api/quiz/1/answer {"answer":2}`},
+		{name: "full URL compared", match: httprequest.MatchURL, method: "GET", path: "/api/quiz?page=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cassette, err := httprequest.LoadCassette(path, tt.match)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := httprequest.WithCassette(context.Background(), cassette)
+			got, err := send(t, ctx, newClient(t), tt.method, otherURL+tt.path, tt.body)
+			if tt.want == "" {
+				if !errors.Is(err, httprequest.ErrInteractionNotFound) {
+					t.Errorf("got %q, %v, want ErrInteractionNotFound", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCassetteReplaysIdenticalRequestsInOrder(t *testing.T) {
+	path := recordCassette(t, [][3]string{
+		{"POST", "/api/quiz/1/answer", `{"answer":1}`},
+		{"POST", "/api/quiz/1/answer", `{"answer":2}`},
+	})
+	cassette, err := httprequest.LoadCassette(path, httprequest.DefaultMatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := httprequest.WithCassette(context.Background(), cassette)
+	for _, want := range []string{`{"answer":1}`, `{"answer":2}`} {
+		got, err := send(t, ctx, newClient(t), httprequest.POST, "http://replay.invalid/api/quiz/1/answer", "")
+		if err != nil || !strings.HasSuffix(got, want) {
+			t.Errorf("got %q, %v, want the response to %s", got, err, want)
+		}
+	}
+	if _, err := send(t, ctx, newClient(t), httprequest.POST, "http://replay.invalid/api/quiz/1/answer", ""); !errors.Is(err, httprequest.ErrInteractionNotFound) {
+		t.Errorf("a consumed interaction was replayed again: %v", err)
+	}
+}
+
+func TestCassetteKeepsTheCallerRequestBody(t *testing.T) {
+	server := echoServer(t)
+	cassette := httprequest.NewCassette(filepath.Join(t.TempDir(), "cassette.json"))
+	transport := cassette.Middleware()(http.DefaultTransport)
+
+	tests := []struct {
+		name    string
+		getBody bool
+	}{
+		{name: "with GetBody", getBody: true},
+		{name: "without GetBody"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := io.NopCloser(bytes.NewReader([]byte(`{"answer":1}`)))
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/api/quiz/1/answer", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.getBody {
+				req.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader(`{"answer":1}`)), nil
+				}
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(got) != `POST /api/quiz/1/answer {"answer":1}` {
+				t.Errorf("the server got %q", got)
+			}
+			if req.Body != body {
+				t.Error("the body of the caller's request was replaced")
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package httprequest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the transport of a Client, to observe or alter every request it sends
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to the http.RoundTripper interface
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// ClientConfig describes the connections of a Client. Zero durations and limits keep the defaults of
+// net/http.
+type ClientConfig struct {
+	// Timeout limits a whole request, response body included
+	Timeout time.Duration
+	// DialTimeout limits the opening of a connection
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout limits the TLS handshake
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits the wait for the response headers once the request is sent
+	ResponseHeaderTimeout time.Duration
+	// IdleConnTimeout closes the pooled connections left idle for that long
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost is how many idle connections are kept for reuse per host
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost caps the connections opened to a host
+	MaxConnsPerHost int
+	// ProxyURL sends every request through the proxy. When empty the HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables apply.
+	ProxyURL string
+	// CABundle is a PEM file of certificate authorities trusted in addition to the system ones
+	CABundle string
+	// RateLimit paces the requests sent to every host, see RateLimiter
+	RateLimit RateLimit
+	// HostRateLimits overrides RateLimit for the listed hosts, such as "api.openai.com"
+	HostRateLimits map[string]RateLimit
+	// Middleware wraps the transport, see Chain. It runs after the rate limit, for every attempt of a
+	// request, and applies to every user of the client.
+	Middleware []Middleware
+}
+
+// DefaultClientConfig returns a 30 seconds timeout, a pool of 10 idle connections per host and a request
+// every two seconds per host
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		RateLimit:           RateLimit{RequestsPerSecond: 0.5, Burst: 1},
+		Timeout:             30 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConnsPerHost: 10,
+	}
+}
+
+// ClientConfigFromEnv returns the default config adjusted by the HTTP_TIMEOUT (a Go duration such as
+// "45s"), HTTP_MAX_CONNS_PER_HOST, HTTP_RATE_LIMIT (requests per second per host, 0 for no limit),
+// HTTP_RATE_BURST, HTTP_PROXY_URL and HTTP_CA_BUNDLE environment variables. HTTP_CORRELATION_HEADER adds
+// the CorrelationID middleware with that header and HTTP_LOG_REQUESTS=true the LogRequests one.
+func ClientConfigFromEnv() (ClientConfig, error) {
+	cfg := DefaultClientConfig()
+	if value := os.Getenv("HTTP_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HTTP_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = timeout
+	}
+	if value := os.Getenv("HTTP_MAX_CONNS_PER_HOST"); value != "" {
+		conns, err := strconv.Atoi(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HTTP_MAX_CONNS_PER_HOST: %w", err)
+		}
+		cfg.MaxConnsPerHost = conns
+	}
+	if value := os.Getenv("HTTP_RATE_LIMIT"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 {
+			return cfg, fmt.Errorf("invalid HTTP_RATE_LIMIT %q: expected a non negative number", value)
+		}
+		cfg.RateLimit.RequestsPerSecond = rate
+	}
+	if value := os.Getenv("HTTP_RATE_BURST"); value != "" {
+		burst, err := strconv.Atoi(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HTTP_RATE_BURST: %w", err)
+		}
+		cfg.RateLimit.Burst = burst
+	}
+	if header := os.Getenv("HTTP_CORRELATION_HEADER"); header != "" {
+		cfg.Middleware = append(cfg.Middleware, CorrelationID(header))
+	}
+	if value := os.Getenv("HTTP_LOG_REQUESTS"); value != "" {
+		logRequests, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HTTP_LOG_REQUESTS: %w", err)
+		}
+		if logRequests {
+			cfg.Middleware = append(cfg.Middleware, LogRequests(slog.LevelInfo))
+		}
+	}
+	cfg.ProxyURL = os.Getenv("HTTP_PROXY_URL")
+	cfg.CABundle = os.Getenv("HTTP_CA_BUNDLE")
+	return cfg, nil
+}
+
+// Client sends the requests of the RPAs through a pool of connections shared by all its users. It is safe
+// for concurrent use. A nil *Client sends the requests with the default client, see SetDefaultClient.
+type Client struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	session    *Session
+	// limiter is waited for before every attempt, outside of the timeout of httpClient
+	limiter *RateLimiter
+}
+
+// NewClient creates a client from the config
+func NewClient(cfg ClientConfig) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	if cfg.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	}
+	if cfg.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if cfg.ProxyURL != "" {
+		proxy, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if cfg.CABundle != "" {
+		pool, err := loadCABundle(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	roundTripper := Chain(cfg.Middleware...)(transport)
+	var limiter *RateLimiter
+	if cfg.RateLimit.RequestsPerSecond > 0 || len(cfg.HostRateLimits) > 0 {
+		limiter = NewRateLimiter(cfg.RateLimit, cfg.HostRateLimits)
+	}
+	return &Client{
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: roundTripper},
+		transport:  roundTripper,
+		limiter:    limiter,
+	}, nil
+}
+
+// loadCABundle returns the system certificate pool extended with the authorities of the PEM file
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// With returns a client sending its requests through the middleware, then through the middleware and the
+// connections of c
+func (c *Client) With(middleware ...Middleware) *Client {
+	c = c.orDefault()
+	transport := Chain(middleware...)(c.transport)
+	return &Client{
+		httpClient: &http.Client{Timeout: c.httpClient.Timeout, Transport: transport, Jar: c.httpClient.Jar},
+		transport:  transport,
+		session:    c.session,
+		limiter:    c.limiter,
+	}
+}
+
+// WithSession returns a client keeping the cookies of the responses in the session and sending the
+// cookies and the tokens of the session with its requests, through the middleware and the connections
+// of c
+func (c *Client) WithSession(session *Session) *Client {
+	client := c.With(session.middleware())
+	client.httpClient.Jar = session.Jar()
+	client.session = session
+	return client
+}
+
+// Session returns the session of the client, nil when it has none
+func (c *Client) Session() *Session {
+	return c.orDefault().session
+}
+
+// HTTPClient returns the underlying client, for code building its own requests. Its requests are not
+// rate limited, see Wait.
+func (c *Client) HTTPClient() *http.Client {
+	return c.orDefault().httpClient
+}
+
+// Wait blocks until the rate limit of the client lets a request go to the host, for the requests sent
+// with HTTPClient
+func (c *Client) Wait(ctx context.Context, host string) error {
+	return c.orDefault().limiter.Wait(ctx, host)
+}
+
+// Do sends the request, retrying it according to the given policy. During a dry run, see WithDryRun,
+// mutating requests are not sent and reads may be answered by fixtures; with a cassette, see WithCassette,
+// requests are recorded or replayed.
+func (c *Client) Do(ctx context.Context, method HTTPMethod, url string, headers Headers, body []byte, policy RetryPolicy) (*http.Response, error) {
+	if dryRun := dryRunFrom(ctx); dryRun != nil {
+		if resp, answered, err := dryRun.respond(ctx, method, url, body); answered {
+			return resp, err
+		}
+	}
+	c = c.orDefault()
+	return doWithRetry(ctx, c.clientFor(ctx), c.limiterFor(ctx), policy, func() (*http.Request, error) {
+		return prepareRequest(ctx, method, url, headers, body)
+	})
+}
+
+// DoBody sends the encoded body, setting the Content-Type header from it
+func (c *Client) DoBody(ctx context.Context, method HTTPMethod, url string, headers Headers, body Body, policy RetryPolicy) (*http.Response, error) {
+	withType := make(Headers, len(headers)+1)
+	for key, value := range headers {
+		if !strings.EqualFold(key, "Content-Type") {
+			withType[key] = value
+		}
+	}
+	withType["Content-Type"] = body.ContentType
+	return c.Do(ctx, method, url, withType, body.Data, policy)
+}
+
+// Get sends a GET request with the default retry policy
+func (c *Client) Get(ctx context.Context, url string, headers Headers) (*http.Response, error) {
+	return c.Do(ctx, GET, url, headers, nil, GetDefaultRetryPolicy())
+}
+
+// Post sends a POST request with the default retry policy
+func (c *Client) Post(ctx context.Context, url string, headers Headers, body []byte) (*http.Response, error) {
+	return c.Do(ctx, POST, url, headers, body, GetDefaultRetryPolicy())
+}
+
+// clientFor returns the client sending the requests made with ctx, going through its cassette if any
+func (c *Client) clientFor(ctx context.Context) *http.Client {
+	cassette := cassetteFrom(ctx)
+	if cassette == nil {
+		return c.httpClient
+	}
+	client := *c.httpClient
+	client.Transport = cassette.Middleware()(c.transport)
+	return &client
+}
+
+// limiterFor returns the rate limiter of the requests made with ctx, none when its cassette replays them
+func (c *Client) limiterFor(ctx context.Context) *RateLimiter {
+	if cassette := cassetteFrom(ctx); cassette != nil && cassette.Mode() == ModeReplay {
+		return nil
+	}
+	return c.limiter
+}
+
+func (c *Client) orDefault() *Client {
+	if c == nil {
+		return DefaultClient()
+	}
+	return c
+}
+
+var (
+	defaultClientMu sync.RWMutex
+	defaultClient   *Client
+)
+
+// SetDefaultClient sets the client used by Do, DoGet, DoPost and every nil *Client
+func SetDefaultClient(client *Client) {
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	defaultClient = client
+}
+
+// DefaultClient returns the client set by SetDefaultClient, or a client with the default config
+func DefaultClient() *Client {
+	defaultClientMu.RLock()
+	client := defaultClient
+	defaultClientMu.RUnlock()
+	if client != nil {
+		return client
+	}
+	defaultClientMu.Lock()
+	defer defaultClientMu.Unlock()
+	if defaultClient == nil {
+		// The default config holds no proxy nor CA bundle, the only settings that can fail
+		defaultClient, _ = NewClient(DefaultClientConfig())
+	}
+	return defaultClient
+}
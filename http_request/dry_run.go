@@ -0,0 +1,152 @@
+package httprequest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+type dryRunContextKey struct{}
+
+// DryRun describes a run that must not change anything on the server. Mutating requests, every method but
+// GET and HEAD, are logged and answered by a fixture or an empty JSON object instead of being sent. Reads are answered
+// by a fixture when one matches and sent to the server otherwise.
+type DryRun struct {
+	Fixtures Fixtures
+}
+
+// NewDryRun creates a dry run answering requests with the fixtures of the file, without fixtures when
+// the file is empty
+func NewDryRun(fixturesFile string) (*DryRun, error) {
+	if fixturesFile == "" {
+		return &DryRun{}, nil
+	}
+	fixtures, err := LoadFixtures(fixturesFile)
+	if err != nil {
+		return nil, err
+	}
+	return &DryRun{Fixtures: fixtures}, nil
+}
+
+// Fixture is a canned response served during a dry run to the requests matching its method and path
+type Fixture struct {
+	// Method of the matched requests, any method when empty
+	Method string `json:"method"`
+	// Path is a pattern in the path.Match syntax, such as "api/quiz/*/start", matched against the end of
+	// the request URL path, so fixtures do not depend on the base URL
+	Path string `json:"path"`
+	// Status of the response, 200 when zero
+	Status int `json:"status"`
+	// Body of the response, as JSON
+	Body json.RawMessage `json:"body"`
+}
+
+// Fixtures are searched in order, the first matching fixture answers the request
+type Fixtures []Fixture
+
+// LoadFixtures reads a JSON array of fixtures from a file
+func LoadFixtures(file string) (Fixtures, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures Fixtures
+	if err := json.Unmarshal(raw, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", file, err)
+	}
+	for i, fixture := range fixtures {
+		if _, err := path.Match(fixture.Path, ""); err != nil {
+			return nil, fmt.Errorf("fixture %d: invalid path %q: %w", i, fixture.Path, err)
+		}
+	}
+	return fixtures, nil
+}
+
+// Match returns the first fixture matching the request
+func (f Fixtures) Match(method HTTPMethod, rawURL string) (Fixture, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return Fixture{}, false
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for _, fixture := range f {
+		if fixture.Method != "" && !strings.EqualFold(fixture.Method, string(method)) {
+			continue
+		}
+		pattern := strings.Trim(fixture.Path, "/")
+		count := strings.Count(pattern, "/") + 1
+		if count > len(segments) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(segments[len(segments)-count:], "/")); ok {
+			return fixture, true
+		}
+	}
+	return Fixture{}, false
+}
+
+// WithDryRun returns a context where the requests sent by a Client follow the dry run
+func WithDryRun(ctx context.Context, dryRun *DryRun) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, dryRun)
+}
+
+// IsDryRun reports whether the requests made with the context are part of a dry run
+func IsDryRun(ctx context.Context) bool {
+	return dryRunFrom(ctx) != nil
+}
+
+// IsMutating reports whether requests with the method may change the state of the server
+func IsMutating(method HTTPMethod) bool {
+	return method != GET && method != HEAD
+}
+
+func dryRunFrom(ctx context.Context) *DryRun {
+	dryRun, _ := ctx.Value(dryRunContextKey{}).(*DryRun)
+	return dryRun
+}
+
+// respond answers the request in place of the server, returning false for reads that must be sent
+func (d *DryRun) respond(ctx context.Context, method HTTPMethod, rawURL string, body []byte) (*http.Response, bool, error) {
+	mutating := IsMutating(method)
+	if mutating {
+		slog.InfoContext(ctx, "Dry run: request not sent", "method", method, "url", secret.Redact(rawURL), "body", secret.Redact(string(body)))
+	}
+	fixture, ok := d.Fixtures.Match(method, rawURL)
+	if !ok && !mutating {
+		return nil, false, nil
+	}
+	if !ok {
+		fixture = Fixture{Body: json.RawMessage("{}")}
+	} else {
+		slog.DebugContext(ctx, "Dry run: response served from fixture", "method", method, "url", rawURL, "fixture", fixture.Path)
+	}
+	req, err := http.NewRequestWithContext(ctx, string(method), rawURL, nil)
+	if err != nil {
+		return nil, true, err
+	}
+	status := fixture.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(fixture.Body)),
+		ContentLength: int64(len(fixture.Body)),
+		Request:       req,
+	}, true, nil
+}
@@ -0,0 +1,97 @@
+package httprequest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// maxErrorBodySize limits how much of the response body is kept in an HTTPError
+const maxErrorBodySize = 1024
+
+// HTTPError describes a response whose status code was not expected
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Status     string
+	// Body holds the beginning of the response body
+	Body string
+	// Message is the error message decoded from the API response, when there is one
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s returned %s", e.Method, e.URL, e.Status)
+	if e.Message != "" {
+		return msg + ": " + e.Message
+	}
+	if e.Body != "" {
+		return msg + ": " + e.Body
+	}
+	return msg
+}
+
+// CheckStatus returns an *HTTPError when the response status is not one of the expected codes.
+// Without expected codes any 2xx status is accepted. The body is consumed when the check fails.
+func CheckStatus(resp *http.Response, expected ...int) error {
+	if len(expected) == 0 && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if slices.Contains(expected, resp.StatusCode) {
+		return nil
+	}
+	return NewHTTPError(resp)
+}
+
+// NewHTTPError builds an HTTPError from the response, reading a truncated copy of its body. The secrets of
+// the URL and the body are redacted, see secret.Redact.
+func NewHTTPError(resp *http.Response) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	if resp.Request != nil {
+		httpErr.Method = resp.Request.Method
+		httpErr.URL = secret.Redact(resp.Request.URL.String())
+	}
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+		httpErr.Message = secret.Redact(decodeErrorMessage(body))
+		if len(body) > maxErrorBodySize {
+			body = append(body[:maxErrorBodySize], "..."...)
+		}
+		httpErr.Body = secret.Redact(strings.TrimSpace(string(body)))
+	}
+	return httpErr
+}
+
+// decodeErrorMessage looks for the usual error fields of a JSON API response
+func decodeErrorMessage(body []byte) string {
+	var payload struct {
+		Message any `json:"message"`
+		Error   any `json:"error"`
+		Detail  any `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	for _, field := range []any{payload.Message, payload.Error, payload.Detail} {
+		switch v := field.(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case map[string]any:
+			if msg, ok := v["message"].(string); ok && msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
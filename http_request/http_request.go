@@ -2,60 +2,79 @@ package httprequest
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"net/http"
-	"time"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
 )
 
 type HTTPMethod string
 
 const (
-	GET  HTTPMethod = "GET"
-	POST HTTPMethod = "POST"
+	GET    HTTPMethod = "GET"
+	POST   HTTPMethod = "POST"
+	PUT    HTTPMethod = "PUT"
+	PATCH  HTTPMethod = "PATCH"
+	DELETE HTTPMethod = "DELETE"
+	HEAD   HTTPMethod = "HEAD"
 )
 
+// Valid reports whether the method is one of the supported methods
+func (m HTTPMethod) Valid() bool {
+	switch m {
+	case GET, POST, PUT, PATCH, DELETE, HEAD:
+		return true
+	}
+	return false
+}
+
+// HasBody reports whether requests with the method carry a body, GET and HEAD never do
+func (m HTTPMethod) HasBody() bool {
+	return m != GET && m != HEAD
+}
+
+// Idempotent reports whether sending a request with the method twice has the effect of sending it once.
+// Only those requests are retried after a network error, which may happen once the server got them.
+func (m HTTPMethod) Idempotent() bool {
+	switch m {
+	case GET, HEAD, PUT, DELETE, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
 // Headers hold the headers for an HTTP request
 type Headers map[string]string
 
-func DoGet(url string, headers Headers) (*http.Response, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	req, err := prepareRequest(http.MethodGet, url, headers, nil)
-	if err != nil {
-		return nil, err
-	}
+// SetSecret sets the header to the value of the secret, which stays redacted from the logs and reports
+func (h Headers) SetSecret(key string, value secret.Secret) {
+	h[key] = value.Reveal()
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+func DoGet(ctx context.Context, url string, headers Headers) (*http.Response, error) {
+	return Do(ctx, GET, url, headers, nil, GetDefaultRetryPolicy())
 }
 
-func DoPost(url string, headers Headers, body []byte) (*http.Response, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	req, err := prepareRequest(http.MethodPost, url, headers, body)
-	if err != nil {
-		return nil, err
-	}
+func DoPost(ctx context.Context, url string, headers Headers, body []byte) (*http.Response, error) {
+	return Do(ctx, POST, url, headers, body, GetDefaultRetryPolicy())
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	return resp, nil
+// Do sends the request with the default client, see Client.Do
+func Do(ctx context.Context, method HTTPMethod, url string, headers Headers, body []byte, policy RetryPolicy) (*http.Response, error) {
+	return DefaultClient().Do(ctx, method, url, headers, body, policy)
 }
 
-func prepareRequest(method string, url string, headers Headers, body []byte) (*http.Request, error) {
-	var req *http.Request
-	var err error
-	if method == http.MethodGet {
-		req, err = http.NewRequest(http.MethodGet, url, nil)
-	} else if method == http.MethodPost {
-		req, err = http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+func prepareRequest(ctx context.Context, method HTTPMethod, url string, headers Headers, body []byte) (*http.Request, error) {
+	if !method.Valid() {
+		return nil, fmt.Errorf("unsupported HTTP method %q", method)
+	}
+	var reader io.Reader
+	if method.HasBody() {
+		reader = bytes.NewReader(body)
 	}
+	req, err := http.NewRequestWithContext(ctx, string(method), url, reader)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,185 @@
+package httprequest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// Chain composes the middleware into one, the first being the outermost: it sees the requests first and
+// the responses last
+func Chain(middleware ...Middleware) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+		return next
+	}
+}
+
+// ForHosts applies the middleware only to the requests sent to one of the hosts
+func ForHosts(middleware Middleware, hosts ...string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		wrapped := middleware(next)
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if slices.Contains(hosts, req.URL.Host) {
+				return wrapped.RoundTrip(req)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// SetHeaders sets the headers on every request that does not carry them already
+func SetHeaders(headers Headers) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			cloned := false
+			for key, value := range headers {
+				if req.Header.Get(key) != "" {
+					continue
+				}
+				if !cloned {
+					req, cloned = req.Clone(req.Context()), true
+				}
+				req.Header.Set(key, value)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context whose requests carry the id in the header set by CorrelationID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFrom returns the correlation id of the context, empty when it has none
+func CorrelationIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// CorrelationID sets the header, such as X-Correlation-ID, on every request, to the id of its context or to
+// a new random id. The id is also added to the request context for the inner middleware.
+func CorrelationID(header string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			id := req.Header.Get(header)
+			if id == "" {
+				id = CorrelationIDFrom(req.Context())
+			}
+			if id == "" {
+				id = newCorrelationID()
+			}
+			req = req.Clone(WithCorrelationID(req.Context(), id))
+			req.Header.Set(header, id)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func newCorrelationID() string {
+	raw := make([]byte, 8)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// LogRequests logs every request at the level with its status, duration and correlation id. Failed
+// requests are logged as warnings.
+func LogRequests(level slog.Level) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			attrs := []any{"method", req.Method, "url", req.URL.String(), "duration", time.Since(start).Round(time.Millisecond)}
+			if id := CorrelationIDFrom(req.Context()); id != "" {
+				attrs = append(attrs, "correlation_id", id)
+			}
+			if err != nil {
+				slog.WarnContext(req.Context(), "HTTP request failed", append(attrs, "error", err)...)
+				return resp, err
+			}
+			slog.Log(req.Context(), level, "HTTP request", append(attrs, "status", resp.StatusCode)...)
+			return resp, nil
+		})
+	}
+}
+
+// Timing passes the duration of every request, until the response headers are received, to observe. The
+// response is nil when err is set.
+func Timing(observe func(req *http.Request, resp *http.Response, duration time.Duration, err error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			observe(req, resp, time.Since(start), err)
+			return resp, err
+		})
+	}
+}
+
+// Exchange is a request and its response captured by CaptureBodies
+type Exchange struct {
+	Method       string
+	URL          string
+	StatusCode   int
+	RequestBody  []byte
+	ResponseBody []byte
+	Duration     time.Duration
+	Err          error
+}
+
+// CaptureBodies passes every exchange to capture, with its URL and copies of the bodies redacted by the secret
+// package, the bodies truncated to limit bytes. The response body is read before being handed on, so it suits
+// the small JSON responses of the APIs.
+func CaptureBodies(limit int, capture func(Exchange)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			exchange := Exchange{Method: req.Method, URL: secret.Redact(req.URL.String())}
+			if req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					data, _ := io.ReadAll(body)
+					_ = body.Close()
+					exchange.RequestBody = truncate(secret.RedactBytes(data), limit)
+				}
+			}
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			exchange.Duration, exchange.Err = time.Since(start), err
+			if resp != nil {
+				exchange.StatusCode = resp.StatusCode
+				if resp.Body != nil {
+					data, readErr := io.ReadAll(resp.Body)
+					_ = resp.Body.Close()
+					exchange.ResponseBody = truncate(secret.RedactBytes(data), limit)
+					if readErr != nil {
+						exchange.Err = readErr
+						capture(exchange)
+						return nil, readErr
+					}
+					resp.Body = io.NopCloser(bytes.NewReader(data))
+				}
+			}
+			capture(exchange)
+			return resp, err
+		})
+	}
+}
+
+func truncate(data []byte, limit int) []byte {
+	if len(data) > limit {
+		data = data[:limit]
+	}
+	return bytes.Clone(data)
+}
@@ -0,0 +1,152 @@
+package httprequest_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// tracing records when the request goes through it and when the response comes back
+func tracing(name string, trace *[]string) httprequest.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httprequest.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*trace = append(*trace, name+" request")
+			resp, err := next.RoundTrip(req)
+			*trace = append(*trace, name+" response")
+			return resp, err
+		})
+	}
+}
+
+// respond answers every request with the body, recording the host
+func respond(body string, trace *[]string) http.RoundTripper {
+	return httprequest.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*trace = append(*trace, "send "+req.URL.Host)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+}
+
+func roundTrip(t *testing.T, transport http.RoundTripper, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	transport := httprequest.Chain(tracing("first", &trace), tracing("second", &trace))(respond("", &trace))
+	roundTrip(t, transport, http.MethodGet, "http://lms.test/", "").Body.Close()
+
+	want := []string{"first request", "second request", "send lms.test", "second response", "first response"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestClientMiddlewareOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var trace []string
+	client, err := httprequest.NewClient(httprequest.ClientConfig{
+		Middleware: []httprequest.Middleware{tracing("config", &trace)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = client.With(tracing("with", &trace))
+	resp, err := client.Get(context.Background(), server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	want := []string{"with request", "config request", "config response", "with response"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestForHosts(t *testing.T) {
+	var trace []string
+	transport := httprequest.ForHosts(tracing("lms", &trace), "lms.test")(respond("", &trace))
+	for _, url := range []string{"http://lms.test/", "http://api.openai.com/"} {
+		roundTrip(t, transport, http.MethodGet, url, "").Body.Close()
+	}
+
+	want := []string{"lms request", "send lms.test", "lms response", "send api.openai.com"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestSetHeadersKeepsTheRequestHeaders(t *testing.T) {
+	var got http.Header
+	transport := httprequest.SetHeaders(httprequest.Headers{"Accept": "application/json", "User-Agent": "rpa"})(
+		httprequest.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			got = req.Header
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}))
+	req, err := http.NewRequest(http.MethodGet, "http://lms.test/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("User-Agent", "caller")
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("Accept") != "application/json" || got.Get("User-Agent") != "caller" {
+		t.Errorf("sent headers %v", got)
+	}
+	if req.Header.Get("Accept") != "" {
+		t.Error("the headers of the caller's request were modified")
+	}
+}
+
+func TestCaptureBodiesRedacts(t *testing.T) {
+	const token = "s3cr3t-session-token"
+	secret.Register(token)
+	t.Cleanup(func() { secret.Unregister(token) })
+
+	var trace []string
+	var exchanges []httprequest.Exchange
+	capture := httprequest.CaptureBodies(1024, func(exchange httprequest.Exchange) {
+		exchanges = append(exchanges, exchange)
+	})
+	transport := capture(respond(`{"token":"`+token+`"}`, &trace))
+	resp := roundTrip(t, transport, http.MethodPost, "http://lms.test/login?token="+token, `{"password":"`+token+`"}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !bytes.Contains(body, []byte(token)) {
+		t.Errorf("the response handed on was redacted: %s", body)
+	}
+	if len(exchanges) != 1 {
+		t.Fatalf("captured %d exchanges, want 1", len(exchanges))
+	}
+	exchange := exchanges[0]
+	for name, captured := range map[string]string{
+		"URL":           exchange.URL,
+		"request body":  string(exchange.RequestBody),
+		"response body": string(exchange.ResponseBody),
+	} {
+		if strings.Contains(captured, token) || !strings.Contains(captured, secret.Redacted) {
+			t.Errorf("captured %s %q, want the token redacted", name, captured)
+		}
+	}
+}
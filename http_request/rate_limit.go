@@ -0,0 +1,109 @@
+package httprequest
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit is the pace of the requests sent to a host. A zero RequestsPerSecond disables the limit.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained rate, such as 0.5 for a request every two seconds
+	RequestsPerSecond float64
+	// Burst is how many requests can be sent at once after an idle period, at least one
+	Burst int
+}
+
+// RateLimiter paces the requests with a token bucket per host: every request takes a token, the bucket
+// holding at most Burst tokens and refilling at RequestsPerSecond. It is safe for concurrent use.
+type RateLimiter struct {
+	limit RateLimit
+	hosts map[string]RateLimit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewRateLimiter creates a limiter applying the limit to every host, unless hosts holds a limit for it
+func NewRateLimiter(limit RateLimit, hosts map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{limit: limit, hosts: hosts, buckets: make(map[string]*bucket)}
+}
+
+// Wait blocks until a request can be sent to the host or the context is done. A nil limiter never waits.
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	if l == nil {
+		return nil
+	}
+	delay, cancel := l.reserve(host, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	slog.DebugContext(ctx, "Rate limit reached, waiting", "host", host, "delay", delay.Round(time.Millisecond))
+	if err := wait(ctx, delay); err != nil {
+		cancel()
+		return err
+	}
+	return nil
+}
+
+// reserve takes a token of the host bucket, returning how long to wait for it and a function giving it
+// back when the request is abandoned
+func (l *RateLimiter) reserve(host string, now time.Time) (time.Duration, func()) {
+	limit, ok := l.hosts[host]
+	if !ok {
+		limit = l.limit
+	}
+	if limit.RequestsPerSecond <= 0 {
+		return 0, func() {}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{rate: limit.RequestsPerSecond, burst: float64(max(limit.Burst, 1)), last: now}
+		b.tokens = b.burst
+		l.buckets[host] = b
+	}
+	b.refill(now)
+	b.tokens--
+	cancel := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		b.tokens++
+	}
+	if b.tokens >= 0 {
+		return 0, cancel
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second)), cancel
+}
+
+// Middleware waits for the rate limit of the host before sending every request. The wait happens inside the
+// transport, so it counts against the timeout of the http.Client; a Client waits before sending instead.
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := l.Wait(req.Context(), req.URL.Host); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// bucket holds the tokens of a host. Tokens go negative when requests are waiting for them.
+type bucket struct {
+	tokens float64
+	burst  float64
+	rate   float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
@@ -0,0 +1,94 @@
+package httprequest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 2, Burst: 2}, nil)
+	start := time.Now()
+	steps := []struct {
+		name      string
+		after     time.Duration
+		wantDelay time.Duration
+	}{
+		{name: "first token of the burst", wantDelay: 0},
+		{name: "second token of the burst", wantDelay: 0},
+		{name: "bucket empty", wantDelay: 500 * time.Millisecond},
+		{name: "queued behind the waiting request", wantDelay: time.Second},
+		{name: "refilled after a second", after: 1500 * time.Millisecond, wantDelay: 0},
+		{name: "refill capped by the burst", after: 10 * time.Second, wantDelay: 0},
+		{name: "second token after the idle period", after: 10 * time.Second, wantDelay: 0},
+		{name: "bucket empty again", after: 10 * time.Second, wantDelay: 500 * time.Millisecond},
+	}
+	for _, step := range steps {
+		delay, _ := limiter.reserve("lms.test", start.Add(step.after))
+		if delay != step.wantDelay {
+			t.Errorf("%s: delay = %s, want %s", step.name, delay, step.wantDelay)
+		}
+	}
+}
+
+func TestRateLimiterCancelGivesTheTokenBack(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 1}, nil)
+	now := time.Now()
+	if delay, _ := limiter.reserve("lms.test", now); delay != 0 {
+		t.Fatalf("first delay = %s, want 0", delay)
+	}
+	delay, cancel := limiter.reserve("lms.test", now)
+	if delay != time.Second {
+		t.Fatalf("second delay = %s, want 1s", delay)
+	}
+	cancel()
+	if delay, _ := limiter.reserve("lms.test", now); delay != time.Second {
+		t.Errorf("delay after the cancellation = %s, want 1s", delay)
+	}
+}
+
+func TestRateLimiterHosts(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 1}, map[string]RateLimit{
+		"api.openai.com": {RequestsPerSecond: 0.5},
+		"cdn.test":       {},
+	})
+	now := time.Now()
+	tests := []struct {
+		host      string
+		wantDelay time.Duration
+	}{
+		{host: "lms.test", wantDelay: 0},
+		{host: "lms.test", wantDelay: time.Second},
+		{host: "other.test", wantDelay: 0},
+		{host: "api.openai.com", wantDelay: 0},
+		{host: "api.openai.com", wantDelay: 2 * time.Second},
+		{host: "cdn.test", wantDelay: 0},
+		{host: "cdn.test", wantDelay: 0},
+	}
+	for _, tt := range tests {
+		if delay, _ := limiter.reserve(tt.host, now); delay != tt.wantDelay {
+			t.Errorf("%s: delay = %s, want %s", tt.host, delay, tt.wantDelay)
+		}
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	var nilLimiter *RateLimiter
+	if err := nilLimiter.Wait(context.Background(), "lms.test"); err != nil {
+		t.Errorf("nil limiter: %v", err)
+	}
+
+	limiter := NewRateLimiter(RateLimit{RequestsPerSecond: 0.1}, nil)
+	if err := limiter.Wait(context.Background(), "lms.test"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "lms.test"); err == nil {
+		t.Fatal("Wait() returned before the token was available")
+	}
+	// the abandoned wait gave its token back, so the next request waits for one token only
+	if delay, _ := limiter.reserve("lms.test", time.Now()); delay > 10*time.Second {
+		t.Errorf("delay = %s, want at most 10s", delay)
+	}
+}
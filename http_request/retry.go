@@ -0,0 +1,207 @@
+package httprequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy describes how a failed request is retried. The zero value performs a single attempt.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, doubled on every following retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and the Retry-After header. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay that is randomized, between 0 and 1
+	Jitter float64
+	// RetryableStatusCodes lists the response status codes that trigger a retry
+	RetryableStatusCodes []int
+	// RetryNetworkErrors retries requests that failed without a response, such as timeouts and resets.
+	// Only the idempotent methods are retried unless RetryNonIdempotent is set.
+	RetryNetworkErrors bool
+	// RetryNonIdempotent also retries POST and PATCH requests after a network error, at the risk of
+	// sending them twice
+	RetryNonIdempotent bool
+	// OnRetry is called before waiting for the next attempt with the attempt that failed and the reason.
+	// When nil the retry is logged as a warning.
+	OnRetry func(attempt int, delay time.Duration, reason error)
+}
+
+// DefaultRetryPolicy returns a policy with three attempts and exponential backoff starting at one second,
+// retrying the network errors of idempotent requests and the 429, 502, 503 and 504 status codes
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            time.Second,
+		MaxDelay:             30 * time.Second,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryNetworkErrors:   true,
+	}
+}
+
+var (
+	defaultPolicyMu sync.RWMutex
+	defaultPolicy   RetryPolicy
+)
+
+// SetDefaultRetryPolicy sets the policy used by DoGet, DoPost and every HTTPTask without its own policy
+func SetDefaultRetryPolicy(policy RetryPolicy) {
+	defaultPolicyMu.Lock()
+	defer defaultPolicyMu.Unlock()
+	defaultPolicy = policy
+}
+
+// GetDefaultRetryPolicy returns the policy set by SetDefaultRetryPolicy
+func GetDefaultRetryPolicy() RetryPolicy {
+	defaultPolicyMu.RLock()
+	defer defaultPolicyMu.RUnlock()
+	return defaultPolicy
+}
+
+// StatusError describes a response whose status code triggered a retry
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "retryable response status: " + e.Status
+}
+
+// doWithRetry sends the request built by newRequest until it succeeds, fails with a non retryable
+// outcome or runs out of attempts. Every attempt first waits for the limiter, if any, so the wait does not
+// count against the client timeout. The last response is returned even when its status was retryable.
+func doWithRetry(ctx context.Context, client *http.Client, limiter *RateLimiter, policy RetryPolicy, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	attempts := max(policy.MaxAttempts, 1)
+	stats := statsFrom(ctx)
+	stats.addRequest()
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		if err := limiter.Wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+
+		var reason error
+		var retryAfter time.Duration
+		switch {
+		case err != nil:
+			if !policy.retriesNetworkError(HTTPMethod(req.Method)) || ctx.Err() != nil || errors.Is(err, ErrInteractionNotFound) {
+				return nil, err
+			}
+			reason = err
+		case slices.Contains(policy.RetryableStatusCodes, resp.StatusCode):
+			reason = &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		default:
+			return resp, nil
+		}
+
+		if attempt >= attempts {
+			if err != nil {
+				return nil, fmt.Errorf("request failed after %d attempts: %w", attempt, err)
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		delay := policy.backoff(attempt, retryAfter)
+		stats.addRetry()
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, delay, reason)
+		} else {
+			slog.WarnContext(ctx, "HTTP attempt failed, retrying", "attempt", attempt, "error", reason, "delay", delay)
+		}
+		if err := wait(ctx, delay); err != nil {
+			return nil, errors.Join(reason, err)
+		}
+	}
+}
+
+// retriesNetworkError reports whether a request with the method is retried after a network error
+func (p RetryPolicy) retriesNetworkError(method HTTPMethod) bool {
+	return p.RetryNetworkErrors && (method.Idempotent() || p.RetryNonIdempotent)
+}
+
+// backoff returns the delay before the attempt following the given one. A Retry-After value
+// longer than the computed backoff takes precedence.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(attempt-1)))
+	if p.Jitter > 0 {
+		spread := float64(delay) * min(p.Jitter, 1)
+		delay += time.Duration(spread * (rand.Float64()*2 - 1))
+	}
+	delay = max(delay, retryAfter)
+	if p.MaxDelay > 0 {
+		delay = min(delay, p.MaxDelay)
+	}
+	return delay
+}
+
+// parseRetryAfter reads a Retry-After header expressed either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryPolicyFromEnv returns the default policy adjusted by the HTTP_MAX_ATTEMPTS and
+// HTTP_RETRY_BASE_DELAY (a Go duration such as "500ms") environment variables
+func RetryPolicyFromEnv() (RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+	if value := os.Getenv("HTTP_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid HTTP_MAX_ATTEMPTS: %w", err)
+		}
+		policy.MaxAttempts = attempts
+	}
+	if value := os.Getenv("HTTP_RETRY_BASE_DELAY"); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil {
+			return policy, fmt.Errorf("invalid HTTP_RETRY_BASE_DELAY: %w", err)
+		}
+		policy.BaseDelay = delay
+	}
+	return policy, nil
+}
@@ -0,0 +1,193 @@
+package httprequest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+)
+
+// newClient returns a client without rate limit
+func newClient(t *testing.T) *httprequest.Client {
+	t.Helper()
+	client, err := httprequest.NewClient(httprequest.ClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// retryPolicy retries the default status codes and network errors after 1ms, recording the delays
+func retryPolicy(delays *[]time.Duration) httprequest.RetryPolicy {
+	policy := httprequest.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.Jitter = 0
+	policy.OnRetry = func(_ int, delay time.Duration, _ error) {
+		*delays = append(*delays, delay)
+	}
+	return policy
+}
+
+// statusSequence answers the requests with the statuses in turn, the last one repeating
+func statusSequence(hits *atomic.Int32, header http.Header, statuses ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hit := int(hits.Add(1))
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(statuses[min(hit, len(statuses))-1])
+	}
+}
+
+func TestRetryStatusCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		wantStatus int
+		wantHits   int32
+	}{
+		{name: "success", statuses: []int{200}, wantStatus: 200, wantHits: 1},
+		{name: "retried until success", statuses: []int{503, 502, 200}, wantStatus: 200, wantHits: 3},
+		{name: "last response once out of attempts", statuses: []int{503}, wantStatus: 503, wantHits: 3},
+		{name: "not retryable", statuses: []int{400, 200}, wantStatus: 400, wantHits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(statusSequence(&hits, nil, tt.statuses...))
+			defer server.Close()
+
+			var delays []time.Duration
+			resp, err := newClient(t).Do(context.Background(), httprequest.GET, server.URL, nil, nil, retryPolicy(&delays))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || hits.Load() != tt.wantHits {
+				t.Errorf("got status %d after %d requests, want %d after %d", resp.StatusCode, hits.Load(), tt.wantStatus, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryBackoffDoubles(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(statusSequence(&hits, nil, 503))
+	defer server.Close()
+
+	var delays []time.Duration
+	policy := retryPolicy(&delays)
+	policy.MaxAttempts = 4
+	policy.MaxDelay = 3 * time.Millisecond
+	resp, err := newClient(t).Do(context.Background(), httprequest.GET, server.URL, nil, nil, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}
+	if len(delays) != len(want) {
+		t.Fatalf("delays = %v, want %v", delays, want)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("delays = %v, want %v", delays, want)
+		}
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxDelay  time.Duration
+		wantDelay time.Duration
+	}{
+		{name: "longer than the backoff", wantDelay: time.Second},
+		{name: "capped by the max delay", maxDelay: 10 * time.Millisecond, wantDelay: 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(statusSequence(&hits, http.Header{"Retry-After": {"1"}}, 429, 200))
+			defer server.Close()
+
+			var delays []time.Duration
+			policy := retryPolicy(&delays)
+			policy.MaxDelay = tt.maxDelay
+			resp, err := newClient(t).Do(context.Background(), httprequest.GET, server.URL, nil, nil, policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != 200 || len(delays) != 1 || delays[0] != tt.wantDelay {
+				t.Errorf("got status %d after delays %v, want 200 after [%s]", resp.StatusCode, delays, tt.wantDelay)
+			}
+		})
+	}
+}
+
+// closeConnection drops the connection without answering, failing the request with a network error
+func closeConnection(hits *atomic.Int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}
+}
+
+func TestRetryNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        httprequest.HTTPMethod
+		nonIdempotent bool
+		networkErrors bool
+		wantHits      int32
+	}{
+		{name: "idempotent method", method: httprequest.GET, networkErrors: true, wantHits: 3},
+		{name: "PUT is idempotent", method: httprequest.PUT, networkErrors: true, wantHits: 3},
+		{name: "POST not retried", method: httprequest.POST, networkErrors: true, wantHits: 1},
+		{name: "PATCH not retried", method: httprequest.PATCH, networkErrors: true, wantHits: 1},
+		{name: "POST retried when opted in", method: httprequest.POST, networkErrors: true, nonIdempotent: true, wantHits: 3},
+		{name: "network errors not retried", method: httprequest.GET, wantHits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(closeConnection(&hits))
+			defer server.Close()
+
+			var delays []time.Duration
+			policy := retryPolicy(&delays)
+			policy.RetryNetworkErrors = tt.networkErrors
+			policy.RetryNonIdempotent = tt.nonIdempotent
+			_, err := newClient(t).Do(context.Background(), tt.method, server.URL, nil, []byte(`{}`), policy)
+			if err == nil {
+				t.Fatal("the request succeeded")
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("sent %d times, want %d", hits.Load(), tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenTheContextIsCancelled(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(statusSequence(&hits, nil, 503))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := httprequest.DefaultRetryPolicy()
+	policy.BaseDelay = time.Minute
+	policy.OnRetry = func(int, time.Duration, error) { cancel() }
+	_, err := newClient(t).Do(ctx, httprequest.GET, server.URL, nil, nil, policy)
+	if !errors.Is(err, context.Canceled) || hits.Load() != 1 {
+		t.Errorf("got %v after %d requests, want context.Canceled after 1", err, hits.Load())
+	}
+}
@@ -0,0 +1,83 @@
+package httprequest
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// DefaultTokenHeader is the header carrying the session token
+const DefaultTokenHeader = "X-Authorization"
+
+// Session holds the credentials obtained by logging in during a run: the cookies set by the website and
+// the tokens issued to each host. A client using the session, see Client.WithSession, stores the cookies
+// of every response and sends the cookies and the token of the host with every request. It is safe for
+// concurrent use.
+type Session struct {
+	jar    http.CookieJar
+	header string
+
+	mu     sync.RWMutex
+	tokens map[string]string
+}
+
+// NewSession creates an empty session sending its tokens in the header, X-Authorization when empty
+func NewSession(tokenHeader string) *Session {
+	if tokenHeader == "" {
+		tokenHeader = DefaultTokenHeader
+	}
+	// cookiejar.New only fails on invalid options
+	jar, _ := cookiejar.New(nil)
+	return &Session{jar: jar, header: tokenHeader, tokens: make(map[string]string)}
+}
+
+// Jar returns the cookie jar of the session
+func (s *Session) Jar() http.CookieJar {
+	return s.jar
+}
+
+// TokenHeader returns the header carrying the token
+func (s *Session) TokenHeader() string {
+	return s.header
+}
+
+// SetToken sets the token sent to the host, replacing the header value of the requests. An empty token
+// removes it. Tokens are only sent to the host they were issued by, and are registered as secrets while
+// the session holds them.
+func (s *Session) SetToken(host, token string) {
+	secret.Register(token)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.tokens[host]
+	if token == "" {
+		delete(s.tokens, host)
+	} else {
+		s.tokens[host] = token
+	}
+	if ok && previous != token {
+		secret.Unregister(previous)
+	}
+}
+
+// Token returns the token of the host
+func (s *Session) Token(host string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[host]
+	return token, ok
+}
+
+// middleware sets the token header of the requests sent to a host with a token
+func (s *Session) middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if token, ok := s.Token(req.URL.Host); ok {
+				req = req.Clone(req.Context())
+				req.Header.Set(s.header, token)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package httprequest
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type statsContextKey struct{}
+
+// RequestStats counts the requests sent and the retries made with a context. Counts also go to the
+// stats of the enclosing context, so the stats of a step include the requests of its sub-steps.
+type RequestStats struct {
+	parent   *RequestStats
+	requests atomic.Int64
+	retries  atomic.Int64
+}
+
+// WithRequestStats returns a context counting the requests made with it in the returned stats
+func WithRequestStats(ctx context.Context) (context.Context, *RequestStats) {
+	stats := &RequestStats{parent: statsFrom(ctx)}
+	return context.WithValue(ctx, statsContextKey{}, stats), stats
+}
+
+// Requests returns how many requests were sent, not counting retries
+func (s *RequestStats) Requests() int {
+	return int(s.requests.Load())
+}
+
+// Retries returns how many times a request was retried
+func (s *RequestStats) Retries() int {
+	return int(s.retries.Load())
+}
+
+func statsFrom(ctx context.Context) *RequestStats {
+	stats, _ := ctx.Value(statsContextKey{}).(*RequestStats)
+	return stats
+}
+
+func (s *RequestStats) addRequest() {
+	for ; s != nil; s = s.parent {
+		s.requests.Add(1)
+	}
+}
+
+func (s *RequestStats) addRetry() {
+	for ; s != nil; s = s.parent {
+		s.retries.Add(1)
+	}
+}
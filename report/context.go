@@ -0,0 +1,69 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+)
+
+type recorderContextKey struct{}
+
+type stepContextKey struct{}
+
+// WithRecorder makes the recorder available to the code executed with the returned context, and counts
+// every HTTP request made with it in the report totals
+func WithRecorder(ctx context.Context, recorder *Recorder) context.Context {
+	ctx, stats := httprequest.WithRequestStats(ctx)
+	recorder.mu.Lock()
+	recorder.stats = stats
+	recorder.mu.Unlock()
+	return context.WithValue(ctx, recorderContextKey{}, recorder)
+}
+
+// FromContext returns the recorder of the run, or nil when the run is not reported
+func FromContext(ctx context.Context) *Recorder {
+	recorder, _ := ctx.Value(recorderContextKey{}).(*Recorder)
+	return recorder
+}
+
+// Begin records a step executed outside of the engine, such as a course task of a usecase rpa. The returned
+// context counts the HTTP requests of the step and carries its path to nested steps and answers; done
+// closes the step with its outcome. Without a recorder in ctx, Begin does nothing.
+func Begin(ctx context.Context, path, name string) (context.Context, func(err error)) {
+	recorder := FromContext(ctx)
+	if recorder == nil {
+		return ctx, func(error) {}
+	}
+	ctx, stats := httprequest.WithRequestStats(ctx)
+	ctx = context.WithValue(ctx, stepContextKey{}, path)
+	step := recorder.begin(path, name, KindTask)
+	return ctx, func(err error) {
+		recorder.end(path, time.Since(step.StartedAt), err)
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		step.HTTPCalls = stats.Requests()
+		step.Retries = stats.Retries()
+	}
+}
+
+// RecordAnswer adds the answer to the report of the run, tagged with the current step. Without a recorder
+// in ctx the answer is dropped.
+func RecordAnswer(ctx context.Context, answer Answer) {
+	recorder := FromContext(ctx)
+	if recorder == nil {
+		return
+	}
+	if answer.Step == "" {
+		answer.Step = stepFrom(ctx)
+	}
+	recorder.RecordAnswer(answer)
+}
+
+func stepFrom(ctx context.Context) string {
+	if path, ok := ctx.Value(stepContextKey{}).(string); ok {
+		return path
+	}
+	return engine.StepPath(ctx)
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Markdown renders the report as a Markdown document with a summary, the steps table and the answers table
+func (r *Report) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Run report: %s\n\n", r.Name)
+	fmt.Fprintf(&sb, "- Status: **%s**\n", r.Status)
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "- Error: %s\n", cell(r.Error))
+	}
+	fmt.Fprintf(&sb, "- Started: %s\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Duration: %s\n", formatDuration(r.Duration))
+	fmt.Fprintf(&sb, "- HTTP calls: %d (%d retries)\n", r.HTTPCalls, r.Retries)
+	if correct, corrected := r.Score(); corrected > 0 {
+		fmt.Fprintf(&sb, "- Correct answers: %d/%d\n", correct, corrected)
+	}
+
+	if len(r.Steps) > 0 {
+		sb.WriteString("\n## Steps\n\n")
+		sb.WriteString("| Step | Kind | Status | Duration | HTTP calls | Retries | Error |\n")
+		sb.WriteString("| --- | --- | --- | --- | --- | --- | --- |\n")
+		for _, step := range r.Steps {
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %d | %d | %s |\n",
+				cell(step.Path), step.Kind, step.Status, formatDuration(step.Duration),
+				step.HTTPCalls, step.Retries, cell(step.Error))
+		}
+	}
+
+	if len(r.Answers) > 0 {
+		sb.WriteString("\n## Answers\n\n")
+		sb.WriteString("| Kind | ID | Question | Option | Correct option | Correct |\n")
+		sb.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, answer := range r.Answers {
+			correctOption, correct := "-", "-"
+			if answer.CorrectOption != nil {
+				correctOption = fmt.Sprint(*answer.CorrectOption)
+			}
+			if answer.Correct != nil {
+				correct = "no"
+				if *answer.Correct {
+					correct = "yes"
+				}
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %d | %d | %s | %s |\n",
+				answer.Kind, cell(answer.ID), answer.Question, answer.Option, correctOption, correct)
+		}
+	}
+	return sb.String()
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// cell escapes the text so it fits in a single table cell
+func cell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// Recorder builds the report of a run, with the secrets of the error messages redacted. Registered as a listener of an engine.Rpa it records every task,
+// element and HTTP call; code running outside of the engine records its steps with Begin. It is safe for
+// concurrent use.
+type Recorder struct {
+	engine.NopListener
+	mu     sync.Mutex
+	report Report
+	steps  map[string]*Step
+	stats  *httprequest.RequestStats
+}
+
+// NewRecorder starts the report of the named run
+func NewRecorder(name string) *Recorder {
+	return &Recorder{
+		report: Report{Name: name, Status: StatusRunning, StartedAt: time.Now()},
+		steps:  make(map[string]*Step),
+	}
+}
+
+// Finish closes the report with the outcome of the run and returns it
+func (r *Recorder) Finish(err error) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.FinishedAt = time.Now()
+	r.report.Duration = r.report.FinishedAt.Sub(r.report.StartedAt)
+	r.report.Status = StatusSucceeded
+	if err != nil {
+		r.report.Status = StatusFailed
+		r.report.Error = secret.Redact(err.Error())
+	}
+	if r.stats != nil {
+		r.report.HTTPCalls = r.stats.Requests()
+		r.report.Retries = r.stats.Retries()
+	}
+	report := r.report
+	report.Steps = append([]*Step(nil), r.report.Steps...)
+	report.Answers = append([]Answer(nil), r.report.Answers...)
+	return &report
+}
+
+// RecordAnswer adds a submitted answer to the report
+func (r *Recorder) RecordAnswer(answer Answer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Answers = append(r.report.Answers, answer)
+}
+
+func (r *Recorder) BeforeTask(_ context.Context, event engine.TaskEvent) {
+	r.begin(event.Step, event.Task, KindTask)
+}
+
+func (r *Recorder) AfterTask(_ context.Context, event engine.TaskEvent) {
+	r.end(event.Step, event.Duration, event.Err)
+}
+
+func (r *Recorder) BeforeElement(_ context.Context, event engine.ElementEvent) {
+	step := r.begin(event.Step, event.Task, KindElement)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	index := event.Index
+	step.Index = &index
+	step.Element = redactElement(event.Element)
+}
+
+func (r *Recorder) AfterElement(_ context.Context, event engine.ElementEvent) {
+	if !event.Skipped {
+		r.end(event.Step, event.Duration, event.Err)
+		return
+	}
+	step := r.begin(event.Step, event.Task, KindElement)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	index := event.Index
+	step.Index = &index
+	step.Element = redactElement(event.Element)
+	step.Status = StatusSkipped
+}
+
+// OnResponse counts the HTTP call and its retries in the step of the HTTP task and in every enclosing step
+func (r *Recorder) OnResponse(_ context.Context, event engine.ResponseEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	retries := max(event.Attempts-1, 0)
+	for path := event.Request.Step; path != ""; path = parentPath(path) {
+		if step, ok := r.steps[path]; ok {
+			step.HTTPCalls++
+			step.Retries += retries
+		}
+	}
+	if r.stats == nil {
+		r.report.HTTPCalls++
+		r.report.Retries += retries
+	}
+}
+
+func (r *Recorder) begin(path, name, kind string) *Step {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	step := &Step{Path: path, Name: name, Kind: kind, Status: StatusRunning, StartedAt: time.Now()}
+	r.steps[path] = step
+	r.report.Steps = append(r.report.Steps, step)
+	return step
+}
+
+func (r *Recorder) end(path string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	step, ok := r.steps[path]
+	if !ok {
+		return
+	}
+	step.Duration = duration
+	step.Status = StatusSucceeded
+	if err != nil {
+		step.Status = StatusFailed
+		step.Error = secret.Redact(err.Error())
+	}
+}
+
+// redactElement redacts the elements printed as text; secret.Secret values marshal redacted by themselves
+func redactElement(element any) any {
+	if text, ok := element.(string); ok {
+		return secret.Redact(text)
+	}
+	return element
+}
+
+// parentPath strips the last task name or element suffix of the path: "a[1]/b" gives "a[1]", then "a"
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, "/[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+// Save finishes the report with the outcome of the run and writes it to path, see Report.WriteFiles.
+// An empty path only finishes it.
+func (r *Recorder) Save(path string, runErr error) error {
+	report := r.Finish(runErr)
+	if path == "" {
+		return nil
+	}
+	return report.WriteFiles(path)
+}
@@ -0,0 +1,127 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status of a run, a task or an element
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+)
+
+// Kinds of steps
+const (
+	KindTask    = "task"
+	KindElement = "element"
+)
+
+// Report describes a complete run: every task and iteration element executed, and the answers submitted
+type Report struct {
+	Name       string        `json:"name"`
+	Status     Status        `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration_ns"`
+	HTTPCalls  int           `json:"http_calls"`
+	Retries    int           `json:"retries"`
+	Steps      []*Step       `json:"steps"`
+	Answers    []Answer      `json:"answers,omitempty"`
+}
+
+// Step is a task or an iteration element of the run. Path is its position in the task tree, such as
+// "process_quizes[12]/process_quiz". HTTP calls and retries include those of the nested steps.
+type Step struct {
+	Path      string        `json:"path"`
+	Name      string        `json:"name"`
+	Kind      string        `json:"kind"`
+	Index     *int          `json:"index,omitempty"`
+	Element   any           `json:"element,omitempty"`
+	Status    Status        `json:"status"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	HTTPCalls int           `json:"http_calls"`
+	Retries   int           `json:"retries"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Answer is an answer submitted to a quiz, an exam or a course test. Correct and CorrectOption are only
+// set when the API returned the correction.
+type Answer struct {
+	Step          string `json:"step,omitempty"`
+	Kind          string `json:"kind"`
+	ID            string `json:"id"`
+	Question      int    `json:"question"`
+	Option        int    `json:"option"`
+	CorrectOption *int   `json:"correct_option,omitempty"`
+	Correct       *bool  `json:"correct,omitempty"`
+}
+
+// Corrected builds the correction of an answer from the correct option returned by the API
+func (a Answer) Corrected(correctOption int) Answer {
+	correct := a.Option == correctOption
+	a.CorrectOption = &correctOption
+	a.Correct = &correct
+	return a
+}
+
+// Score returns how many corrected answers were right, and how many answers were corrected
+func (r *Report) Score() (correct, corrected int) {
+	for _, answer := range r.Answers {
+		if answer.Correct == nil {
+			continue
+		}
+		corrected++
+		if *answer.Correct {
+			correct++
+		}
+	}
+	return correct, corrected
+}
+
+// WriteFiles writes the report as JSON to path and as Markdown next to it, replacing the .json extension
+// with .md, or appending .md to any other extension. A path ending in .md is rejected.
+func (r *Report) WriteFiles(path string) error {
+	mdPath, err := markdownPath(path)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	if err := os.WriteFile(mdPath, []byte(r.Markdown()), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// markdownPath returns the path of the Markdown copy of the JSON report written to path
+func markdownPath(path string) (string, error) {
+	switch ext := filepath.Ext(path); {
+	case strings.EqualFold(ext, ".md"):
+		return "", fmt.Errorf("invalid report path %s: the JSON report cannot have the .md extension", path)
+	case strings.EqualFold(ext, ".json"):
+		return strings.TrimSuffix(path, ext) + ".md", nil
+	default:
+		return path + ".md", nil
+	}
+}
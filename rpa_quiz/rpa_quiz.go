@@ -5,37 +5,78 @@ import (
 	"os"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/luizhenriquees/go-http-rpa/engine"
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
 )
 
-const (
-	maxPerExec = "maxPerExec"
-	quizPath   = "api/quiz/"
-	// DefaultWaitTime Default wait time between operations
-	DefaultWaitTime = 2 * time.Second
+var (
+	maxPerExecKey = engine.NewKey[int]("maxPerExec")
+	logger        = engine.NewLogger("RPA Quiz")
 )
 
+const quizPath = "api/quiz/"
+
+// Option customizes the quiz rpa
+type Option func(*engine.Rpa)
+
+// WithJournal records the run progress in the journal, so an interrupted run can be resumed
+// without fetching the quizzes again nor re-submitting the quizzes and answers already done
+func WithJournal(journal *engine.Journal) Option {
+	return func(rpa *engine.Rpa) {
+		rpa.SetJournal(journal, quizIdsKey, maxPerExecKey)
+	}
+}
+
+// WithDryRun runs the quizzes without starting nor answering them on the website, see engine.Rpa.SetDryRun
+func WithDryRun(dryRun *httprequest.DryRun) Option {
+	return func(rpa *engine.Rpa) {
+		rpa.SetDryRun(dryRun)
+	}
+}
+
+// WithClient sends the requests of the quiz rpa with the client
+func WithClient(client *httprequest.Client) Option {
+	return func(rpa *engine.Rpa) {
+		rpa.SetClient(client)
+	}
+}
+
+// WithLogin logs the quiz rpa in before fetching the quizzes, see engine.Rpa.SetLogin
+func WithLogin(cfg engine.LoginConfig) Option {
+	return func(rpa *engine.Rpa) {
+		rpa.SetLogin(cfg)
+	}
+}
+
 // NewRpaQuiz creates a complete job for answering quizzes
-func NewRpaQuiz() *engine.Rpa {
+func NewRpaQuiz(opts ...Option) *engine.Rpa {
 	defaultHeaders := make(httprequest.Headers)
 	defaultHeaders["Content-Type"] = "application/json"
-	defaultHeaders["X-Authorization"] = os.Getenv("WEBSITE_TOKEN")
+	defaultHeaders.SetSecret("X-Authorization", secret.New(os.Getenv("WEBSITE_TOKEN")))
 
 	maxPerExecution := getMaxPerExecution()
 	todoQuizIds := buildQuizIdList(maxPerExecution)
 	baseURL := os.Getenv("WEBSITE_URL")
 	rpa := engine.NewRpa("rpa_quiz", baseURL, defaultHeaders)
-	rpa.SetParams(engine.Parameters{
-		quizIdsKey:          todoQuizIds,
-		engine.ParamBaseURL: baseURL,
-		maxPerExec:          maxPerExecution,
-	})
-
-	rpa.AddTask(NewTaskFetchQuizzes(defaultHeaders, rpa.GetParams()))
-	rpa.AddTask(NewTaskProcessQuizes(defaultHeaders, rpa.GetParams()))
+	rpa.SetParams(engine.NewParameters(map[string]any{
+		quizIdsKey.Name():        todoQuizIds,
+		engine.BaseURLKey.Name(): baseURL,
+		maxPerExecKey.Name():     maxPerExecution,
+	}))
+
+	// The quizzes are only fetched when no quiz ids were requested through WEBSITE_QUIZES_ID
+	rpa.AddTask(engine.NewIfTask(
+		"fetch_quizzes_if_none_requested",
+		rpa.GetParams(),
+		engine.Not(engine.HasValue(quizIdsKey.Name())),
+		NewTaskFetchQuizzes(defaultHeaders, rpa.GetParams()),
+	))
+	rpa.AddTask(NewTaskProcessQuizes(defaultHeaders, rpa.GetParams(), getConcurrency(), getMaxFailures()))
+	for _, opt := range opts {
+		opt(rpa)
+	}
 	return rpa
 }
 
@@ -44,15 +85,15 @@ func buildQuizIdList(maxPerExecution int) []string {
 	var todoQuizIds []string
 	if quizesIdStr != "" {
 		requestedQuizIds := strings.Split(quizesIdStr, ",")
-		log.Printf("Requested Quiz IDs list: %v\n", requestedQuizIds)
+		logger.Info("Requested Quiz IDs list: %v", requestedQuizIds)
 		for i := 0; i < maxPerExecution; i++ {
-			if len(requestedQuizIds) < i {
+			if i >= len(requestedQuizIds) {
 				break
 			}
 			todoQuizIds = append(todoQuizIds, requestedQuizIds[i])
 		}
 	}
-	log.Printf("Quiz IDs to do list: %v\n", todoQuizIds)
+	logger.Info("Quiz IDs to do list: %v", todoQuizIds)
 	return todoQuizIds
 }
 
@@ -67,3 +108,29 @@ func getMaxPerExecution() int {
 	}
 	return maxPerExecutionInt
 }
+
+// getMaxFailures reads after how many failed quizzes the run gives up, zero meaning it never does
+func getMaxFailures() int {
+	maxFailures := os.Getenv("QUIZ_MAX_FAILURES")
+	if maxFailures == "" {
+		return 0
+	}
+	maxFailuresInt, err := strconv.Atoi(maxFailures)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return maxFailuresInt
+}
+
+// getConcurrency reads how many quizzes are processed at the same time, defaulting to one
+func getConcurrency() int {
+	concurrency := os.Getenv("QUIZ_CONCURRENCY")
+	if concurrency == "" {
+		return 1
+	}
+	concurrencyInt, err := strconv.Atoi(concurrency)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return concurrencyInt
+}
@@ -1,6 +1,7 @@
 package rpaquiz
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -10,6 +11,16 @@ import (
 	"github.com/luizhenriquees/go-http-rpa/engine"
 	"github.com/luizhenriquees/go-http-rpa/entity"
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/report"
+)
+
+// answerQuizURL is rendered from the rpa parameters and the quiz ID of the current iteration
+const answerQuizURL = "{{.baseUrl}}" + quizPath + "{{.quizIds_currentElement}}/answer"
+
+var (
+	currentQuizIDKey = engine.CurrentElementKey[string](quizIdsKey.Name())
+	// selectedAnswerKey holds the option submitted for the current question
+	selectedAnswerKey = engine.NewKey[int]("selectedAnswer")
 )
 
 // TaskAnswerQuestion is a task to answer a question from quiz
@@ -17,13 +28,15 @@ type TaskAnswerQuestion struct {
 	*engine.HTTPTask
 }
 
-func NewTaskAnswerQuestion(headers httprequest.Headers, params engine.Parameters) *TaskAnswerQuestion {
+func NewTaskAnswerQuestion(headers httprequest.Headers, params *engine.Parameters) *TaskAnswerQuestion {
 	task := &TaskAnswerQuestion{}
 	httpTask := engine.NewHTTPTask(
 		"answer_question",
 		httprequest.POST,
 		headers,
 		params,
+		engine.WithURLTemplate(answerQuizURL),
+		engine.WithRequiredKeys(engine.BaseURLKey, currentQuestionKey, currentQuestionIndexKey),
 		engine.WithPreRequestFunc(task.preRequest),
 		engine.WithPostExtractFunc(task.postExtract),
 	)
@@ -31,64 +44,59 @@ func NewTaskAnswerQuestion(headers httprequest.Headers, params engine.Parameters
 	return task
 }
 
-func (t *TaskAnswerQuestion) preRequest() error {
-	answerURL := t.getAnswerURL()
-	t.Logger.Info("pre-request built URL: %s", answerURL)
-	t.URL = answerURL
-
-	var currentQuestion entity.Question
-	if val, ok := t.Params.Get(questionsKey + "_" + engine.CurrentElement).(entity.Question); ok {
-		currentQuestion = val
+func (t *TaskAnswerQuestion) preRequest(ctx context.Context) error {
+	currentQuestion, err := currentQuestionKey.Get(t.Params)
+	if err != nil {
+		return err
 	}
-	index := 0
-	if val, ok := t.Params.Get(questionsKey + "_" + engine.CurrentIndex).(int); ok {
-		index = val
+	index, err := currentQuestionIndexKey.Get(t.Params)
+	if err != nil {
+		return err
 	}
-	payload := t.createAnswerPayload(len(currentQuestion.Options), index)
+	payload, answerIndex := t.createAnswerPayload(len(currentQuestion.Options), index)
 	t.RequestBody = []byte(payload)
+	selectedAnswerKey.Put(t.Params, answerIndex)
 
-	t.Logger.Info("Question %d: %s", index, currentQuestion.Question)
-	t.Logger.Info("Number of possible answers: %d", len(currentQuestion.Options))
-	t.Logger.Info("Selected answer: %s", payload)
+	logger := engine.ContextLogger(ctx, t.Logger)
+	logger.Info("Question %d: %s", index, currentQuestion.Question)
+	logger.Debug("Number of possible answers: %d", len(currentQuestion.Options))
+	logger.Info("Selected answer: %s", payload)
 	return nil
 }
 
-func (t *TaskAnswerQuestion) getAnswerURL() string {
-	baseURL := ""
-	if val, ok := t.Params.Get(engine.ParamBaseURL).(string); ok {
-		baseURL = val
-	}
-	quizID := ""
-	if val, ok := t.Params.Get(quizIdsKey + "_" + engine.CurrentElement).(string); ok {
-		quizID = val
-	}
-	answerURL := baseURL + quizPath + quizID + "/answer"
-	return answerURL
-}
-
-func (t *TaskAnswerQuestion) createAnswerPayload(optionsCount int, questionIndex int) string {
+func (t *TaskAnswerQuestion) createAnswerPayload(optionsCount int, questionIndex int) (string, int) {
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 	answerIndex := rng.Intn(optionsCount)
-	return fmt.Sprintf(`{"answer":%d,"question_index":%d}`, answerIndex, questionIndex)
+	return fmt.Sprintf(`{"answer":%d,"question_index":%d}`, answerIndex, questionIndex), answerIndex
 }
 
-func (t *TaskAnswerQuestion) postExtract(resp *http.Response, _ *engine.HTTPTask) error {
-	t.Logger.Info("PostExtract used.")
+func (t *TaskAnswerQuestion) postExtract(ctx context.Context, resp *http.Response, _ *engine.HTTPTask) error {
+	logger := engine.ContextLogger(ctx, t.Logger)
+	logger.Debug("PostExtract used.")
 	defer resp.Body.Close()
 	var responseData entity.QuizData
 	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("response not OK: %s", resp.Status)
-	}
 
-	index := 0
-	if val, ok := t.Params.Get(questionsKey + "_" + engine.CurrentIndex).(int); ok {
-		index = val
+	index, err := currentQuestionIndexKey.Get(t.Params)
+	if err != nil {
+		return err
+	}
+	if index >= len(responseData.Questions) {
+		return fmt.Errorf("response has no result for question %d", index)
+	}
+	result := responseData.Questions[index]
+	quizID, err := currentQuizIDKey.Get(t.Params)
+	if err != nil {
+		return err
+	}
+	selected, err := selectedAnswerKey.Get(t.Params)
+	if err != nil {
+		return err
 	}
-	t.Logger.Info("Question %d result - Correct: %d, Answered: %d",
-		index, responseData.Questions[index].Correct, responseData.Questions[index].Answered)
-	t.Logger.Info("=====================================================")
+	answer := report.Answer{Kind: "quiz", ID: quizID, Question: index, Option: selected}.Corrected(result.Correct)
+	report.RecordAnswer(ctx, answer)
+	logger.Info("Question %d result - Correct: %d, Answered: %d", index, result.Correct, selected)
 	return nil
 }
@@ -6,17 +6,21 @@ import (
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
 )
 
-const questionsKey = "questions"
+var (
+	questionsKey            = engine.NewKey[[]entity.Question]("questions")
+	currentQuestionKey      = engine.CurrentElementKey[entity.Question](questionsKey.Name())
+	currentQuestionIndexKey = engine.CurrentIndexKey(questionsKey.Name())
+)
 
 type TaskAnswerQuestions struct {
 	*engine.IterableTask[entity.Question]
 }
 
-func NewTaskAnswerQuestions(headers httprequest.Headers, params engine.Parameters) *TaskAnswerQuestions {
+func NewTaskAnswerQuestions(headers httprequest.Headers, params *engine.Parameters) *TaskAnswerQuestions {
 	return &TaskAnswerQuestions{
 		IterableTask: engine.NewIterableTask[entity.Question](
 			"answer_questions",
-			questionsKey,
+			questionsKey.Name(),
 			NewTaskAnswerQuestion(headers, params),
 			params,
 		),
@@ -1,9 +1,9 @@
 package rpaquiz
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 
@@ -13,8 +13,8 @@ import (
 )
 
 const (
-	fetchQuizPath string            = "api/quiz"
-	pending       entity.QuizStatus = "pending"
+	fetchQuizURL string            = "{{.baseUrl}}api/quiz"
+	pending      entity.QuizStatus = "pending"
 )
 
 // TaskFetchQuizzes is a task to fetch all available quizzes
@@ -22,39 +22,30 @@ type TaskFetchQuizzes struct {
 	*engine.HTTPTask
 }
 
-func NewTaskFetchQuizzes(headers httprequest.Headers, params engine.Parameters) *TaskFetchQuizzes {
+func NewTaskFetchQuizzes(headers httprequest.Headers, params *engine.Parameters) *TaskFetchQuizzes {
 	task := &TaskFetchQuizzes{}
 	httpTask := engine.NewHTTPTask(
 		"fetch_quizzes",
 		httprequest.GET,
 		headers,
 		params,
-		engine.WithPreRequestFunc(task.preRequest),
+		engine.WithURLTemplate(fetchQuizURL),
+		engine.WithRequiredKeys(engine.BaseURLKey, maxPerExecKey),
 		engine.WithPostExtractFunc(task.postExtract),
 	)
 	task.HTTPTask = httpTask
 	return task
 }
 
-func (t *TaskFetchQuizzes) preRequest() error {
-	baseURL := ""
-	if val, ok := t.Params.Get(engine.ParamBaseURL).(string); ok {
-		baseURL = val
-	}
-	fetchQuizURL := baseURL + fetchQuizPath
-	t.Logger.Info("pre-request built URL: %s", fetchQuizURL)
-	t.URL = fetchQuizURL
-	return nil
-}
-
-func (t *TaskFetchQuizzes) postExtract(resp *http.Response, _ *engine.HTTPTask) error {
-	t.Logger.Info("PostExtract used.")
+func (t *TaskFetchQuizzes) postExtract(ctx context.Context, resp *http.Response, _ *engine.HTTPTask) error {
+	logger := engine.ContextLogger(ctx, t.Logger)
+	logger.Debug("PostExtract used.")
 	defer resp.Body.Close()
-	maxPerExecution := t.Params.Get(maxPerExec).(int)
-	pendingQuizIds := t.Params.Get(quizIdsKey).([]string)
-	if len(pendingQuizIds) > 0 {
-		return nil
+	maxPerExecution, err := maxPerExecKey.Get(t.Params)
+	if err != nil {
+		return err
 	}
+	var pendingQuizIds []string
 	var quizList entity.QuizList
 	if err := json.NewDecoder(resp.Body).Decode(&quizList); err != nil {
 		return fmt.Errorf("failed to decode quiz list: %w", err)
@@ -67,7 +58,7 @@ func (t *TaskFetchQuizzes) postExtract(resp *http.Response, _ *engine.HTTPTask)
 			pendingQuizIds = append(pendingQuizIds, strconv.Itoa(quiz.ID))
 		}
 	}
-	t.Params.Put(quizIdsKey, pendingQuizIds)
-	log.Printf("Quiz IDs to do list: %v\n", pendingQuizIds)
+	quizIdsKey.Put(t.Params, pendingQuizIds)
+	logger.Info("Quiz IDs to do list: %v", pendingQuizIds)
 	return nil
 }
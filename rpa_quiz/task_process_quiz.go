@@ -9,7 +9,7 @@ type TaskProcessQuiz struct {
 	*engine.PipelinedTasks
 }
 
-func NewTaskProcessQuiz(headers httprequest.Headers, params engine.Parameters) *TaskProcessQuiz {
+func NewTaskProcessQuiz(headers httprequest.Headers, params *engine.Parameters) *TaskProcessQuiz {
 	pipeline := &TaskProcessQuiz{
 		PipelinedTasks: engine.NewPipelinedTasks(
 			"process_quiz",
@@ -17,6 +17,8 @@ func NewTaskProcessQuiz(headers httprequest.Headers, params engine.Parameters) *
 		),
 	}
 
+	// The questions of a quiz only live while the quiz is processed
+	pipeline.Scoped(params)
 	pipeline.AddTask(NewTaskStartQuiz(headers, params))
 	pipeline.AddTask(NewTaskAnswerQuestions(headers, params))
 	return pipeline
@@ -5,19 +5,29 @@ import (
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
 )
 
-const quizIdsKey = "quizIds"
+var quizIdsKey = engine.NewKey[[]string]("quizIds")
 
 type TaskProcessQuizes struct {
 	*engine.IterableTask[string]
 }
 
-func NewTaskProcessQuizes(headers httprequest.Headers, params engine.Parameters) *TaskProcessQuizes {
+// NewTaskProcessQuizes creates the iteration over the quiz IDs. With a concurrency greater than one,
+// several quizzes are processed at once, each with its own parameters and task instances.
+// A failing quiz does not stop the others; the iteration only gives up once maxFailures quizzes failed,
+// or never when maxFailures is zero. The failed quiz IDs are left under "quizIds_failedElements".
+func NewTaskProcessQuizes(headers httprequest.Headers, params *engine.Parameters, concurrency, maxFailures int) *TaskProcessQuizes {
+	newProcessQuiz := func(scope *engine.Parameters) engine.Task {
+		return NewTaskProcessQuiz(headers, scope)
+	}
 	return &TaskProcessQuizes{
 		IterableTask: engine.NewIterableTask[string](
 			"process_quizes",
-			quizIdsKey,
+			quizIdsKey.Name(),
 			NewTaskProcessQuiz(headers, params),
 			params,
+			engine.WithConcurrency(concurrency, newProcessQuiz),
+			engine.WithMaxFailures(maxFailures),
+			engine.WithLogField("quiz_id"),
 		),
 	}
 }
@@ -0,0 +1,91 @@
+package secret_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+func newLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(secret.NewHandler(slog.NewTextHandler(buf, nil)))
+}
+
+func TestHandlerRedacts(t *testing.T) {
+	const token = "registered-session-token"
+	secret.Register(token)
+	t.Cleanup(func() { secret.Unregister(token) })
+
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+		want string
+	}{
+		{
+			name: "message",
+			log:  func(l *slog.Logger) { l.Info("logged in with " + token) },
+			want: `msg="logged in with [REDACTED]"`,
+		},
+		{
+			name: "string value",
+			log:  func(l *slog.Logger) { l.Info("request", "url", "/api?session="+token) },
+			want: `url="/api?session=[REDACTED]"`,
+		},
+		{
+			name: "sensitive key",
+			log:  func(l *slog.Logger) { l.Info("request", "Authorization", "Basic YWxhZGRpbg==", "attempt", 2) },
+			want: "Authorization=[REDACTED] attempt=2",
+		},
+		{
+			name: "sensitive key of another kind",
+			log:  func(l *slog.Logger) { l.Info("request", "password", 12345678) },
+			want: "password=[REDACTED]",
+		},
+		{
+			name: "group",
+			log: func(l *slog.Logger) {
+				l.Info("request", slog.Group("headers", "Cookie", "session=abc", "Accept", "text/html"))
+			},
+			want: "headers.Cookie=[REDACTED] headers.Accept=text/html",
+		},
+		{
+			name: "error",
+			log:  func(l *slog.Logger) { l.Error("request failed", "error", errors.New("token "+token+" expired")) },
+			want: `error="token [REDACTED] expired"`,
+		},
+		{
+			name: "with attrs",
+			log:  func(l *slog.Logger) { l.With("token", "abc123", "user", "ana").Info("step") },
+			want: "token=[REDACTED] user=ana",
+		},
+		{
+			name: "with group",
+			log:  func(l *slog.Logger) { l.WithGroup("login").Info("step", "password", "hunter22") },
+			want: "login.password=[REDACTED]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(newLogger(&buf))
+			got := buf.String()
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("logged %q, want it to contain %q", got, tt.want)
+			}
+			if strings.Contains(got, token) {
+				t.Errorf("logged the registered token: %q", got)
+			}
+		})
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	handler := secret.NewHandler(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	if handler.Enabled(context.Background(), slog.LevelInfo) || !handler.Enabled(context.Background(), slog.LevelWarn) {
+		t.Error("the handler does not follow the level of the wrapped handler")
+	}
+}
@@ -0,0 +1,206 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// minValueLength is the length under which registered values are not redacted, to keep short values from
+// masking ordinary text
+const minValueLength = 4
+
+// DefaultFields lists the fields whose values are always redacted: JSON keys, form and query parameters,
+// and headers
+var DefaultFields = []string{
+	"password", "passwd", "secret", "client_secret", "token", "access_token", "refresh_token", "id_token",
+	"api_key", "apikey", "authorization", "x-authorization", "cookie", "set-cookie",
+}
+
+// DefaultPatterns match bearer tokens and OpenAI keys
+var DefaultPatterns = []string{
+	`(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`,
+	`sk-[A-Za-z0-9_\-]{16,}`,
+}
+
+// Config lists what is redacted besides the registered values
+type Config struct {
+	// Fields are the names, compared case-insensitively, of the fields whose values are redacted
+	Fields []string
+	// Patterns are regular expressions whose matches are redacted
+	Patterns []*regexp.Regexp
+}
+
+// DefaultConfig returns the default fields and patterns
+func DefaultConfig() Config {
+	cfg := Config{Fields: slices.Clone(DefaultFields)}
+	for _, pattern := range DefaultPatterns {
+		cfg.Patterns = append(cfg.Patterns, regexp.MustCompile(pattern))
+	}
+	return cfg
+}
+
+// ConfigFromEnv returns the default config extended by the REDACT_FIELDS (comma separated names) and
+// REDACT_PATTERNS (comma separated regular expressions) environment variables
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	for _, field := range splitList(os.Getenv("REDACT_FIELDS")) {
+		cfg.Fields = append(cfg.Fields, field)
+	}
+	for _, pattern := range splitList(os.Getenv("REDACT_PATTERNS")) {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid REDACT_PATTERNS entry %q: %w", pattern, err)
+		}
+		cfg.Patterns = append(cfg.Patterns, compiled)
+	}
+	return cfg, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Redactor masks the registered values, the values of the sensitive fields and the matches of the
+// patterns. It is safe for concurrent use.
+type Redactor struct {
+	mu       sync.RWMutex
+	values   []string
+	fields   map[string]bool
+	fieldsRe *regexp.Regexp
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor creates a redactor for the config
+func NewRedactor(cfg Config) *Redactor {
+	r := &Redactor{}
+	r.Configure(cfg)
+	return r
+}
+
+// Configure replaces the fields and the patterns, keeping the registered values
+func (r *Redactor) Configure(cfg Config) {
+	fields := make(map[string]bool, len(cfg.Fields))
+	quoted := make([]string, 0, len(cfg.Fields))
+	for _, field := range cfg.Fields {
+		fields[strings.ToLower(field)] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	var fieldsRe *regexp.Regexp
+	if len(quoted) > 0 {
+		// A field name followed by its value, as in `"token": "abc"`, `token=abc` or `Cookie: abc`
+		fieldsRe = regexp.MustCompile(`(?i)((?:^|[^\w-])(?:` + strings.Join(quoted, "|") + `)["']?\s*[:=]\s*["']?)([^"'&\s,;}]+)`)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fields = fields
+	r.fieldsRe = fieldsRe
+	r.patterns = slices.Clone(cfg.Patterns)
+}
+
+// Register adds values to redact. Empty and very short values are ignored.
+func (r *Redactor) Register(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if len(value) < minValueLength || value == Redacted || slices.Contains(r.values, value) {
+			continue
+		}
+		// Longer values first, so a value containing another one is masked whole
+		i, _ := slices.BinarySearchFunc(r.values, value, func(registered, value string) int {
+			return len(value) - len(registered)
+		})
+		r.values = slices.Insert(r.values, i, value)
+	}
+}
+
+// Unregister removes values that no longer need to be redacted, such as a token replaced by a new one
+func (r *Redactor) Unregister(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if i := slices.Index(r.values, value); i >= 0 {
+			r.values = slices.Delete(r.values, i, i+1)
+		}
+	}
+}
+
+// IsSensitive reports whether the values of the field, such as a header or a JSON key, are redacted
+func (r *Redactor) IsSensitive(field string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fields[strings.ToLower(field)]
+}
+
+// Redact returns the text with the secrets replaced by Redacted
+func (r *Redactor) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, value := range r.values {
+		text = strings.ReplaceAll(text, value, Redacted)
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllLiteralString(text, Redacted)
+	}
+	if r.fieldsRe != nil {
+		text = r.fieldsRe.ReplaceAllString(text, "${1}"+Redacted)
+	}
+	return text
+}
+
+// RedactBytes returns a redacted copy of the data
+func (r *Redactor) RedactBytes(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+	return []byte(r.Redact(string(data)))
+}
+
+var defaultRedactor = NewRedactor(DefaultConfig())
+
+// Default returns the redactor used by the package functions
+func Default() *Redactor {
+	return defaultRedactor
+}
+
+// Configure replaces the fields and the patterns of the default redactor
+func Configure(cfg Config) {
+	defaultRedactor.Configure(cfg)
+}
+
+// Register adds values to the default redactor
+func Register(values ...string) {
+	defaultRedactor.Register(values...)
+}
+
+// Unregister removes values from the default redactor
+func Unregister(values ...string) {
+	defaultRedactor.Unregister(values...)
+}
+
+// Redact redacts the text with the default redactor
+func Redact(text string) string {
+	return defaultRedactor.Redact(text)
+}
+
+// RedactBytes redacts the data with the default redactor
+func RedactBytes(data []byte) []byte {
+	return defaultRedactor.RedactBytes(data)
+}
+
+// IsSensitive reports whether the default redactor redacts the values of the field
+func IsSensitive(field string) bool {
+	return defaultRedactor.IsSensitive(field)
+}
@@ -0,0 +1,153 @@
+package secret_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+func TestRedactorRegisteredValues(t *testing.T) {
+	r := secret.NewRedactor(secret.Config{})
+	r.Register("abc", "", "hunter22", "hunter2222")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: ""},
+		{text: "nothing to hide", want: "nothing to hide"},
+		{text: "password hunter22 sent", want: "password [REDACTED] sent"},
+		{text: "hunter22 and hunter22", want: "[REDACTED] and [REDACTED]"},
+		{text: "the longer hunter2222 is masked whole", want: "the longer [REDACTED] is masked whole"},
+		{text: "short values such as abc are ignored", want: "short values such as abc are ignored"},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+	if got := string(r.RedactBytes([]byte("hunter22"))); got != secret.Redacted {
+		t.Errorf("RedactBytes() = %q, want %q", got, secret.Redacted)
+	}
+}
+
+func TestRedactorUnregisterAfterRotation(t *testing.T) {
+	r := secret.NewRedactor(secret.Config{})
+	r.Register("old-session-token")
+	r.Register("new-session-token")
+	r.Unregister("old-session-token")
+
+	got := r.Redact("old-session-token replaced by new-session-token")
+	if want := "old-session-token replaced by [REDACTED]"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+	r.Unregister("never-registered")
+}
+
+func TestRedactorSensitiveFields(t *testing.T) {
+	r := secret.NewRedactor(secret.DefaultConfig())
+
+	for field, want := range map[string]bool{
+		"password":        true,
+		"X-Authorization": true,
+		"Set-Cookie":      true,
+		"username":        false,
+		"token_type":      false,
+	} {
+		if got := r.IsSensitive(field); got != want {
+			t.Errorf("IsSensitive(%q) = %t, want %t", field, got, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "JSON", text: `{"login":"ana","password":"p4ssw0rd"}`, want: `{"login":"ana","password":"[REDACTED]"}`},
+		{name: "JSON with spaces", text: `{"access_token": "eyJhbGci", "expires_in": 3600}`, want: `{"access_token": "[REDACTED]", "expires_in": 3600}`},
+		{name: "query", text: "/login?user=ana&token=abc123&page=2", want: "/login?user=ana&token=[REDACTED]&page=2"},
+		{name: "header", text: "Cookie: session=abc123", want: "Cookie: [REDACTED]"},
+		{name: "case-insensitive", text: "API_KEY=abc123", want: "API_KEY=[REDACTED]"},
+		{name: "part of another name", text: `{"token_type":"bearer","my-token":"abc"}`, want: `{"token_type":"bearer","my-token":"abc"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactorPatterns(t *testing.T) {
+	r := secret.NewRedactor(secret.DefaultConfig())
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Bearer eyJhbGciOiJIUzI1NiJ9.e30.sig", want: "[REDACTED]"},
+		{text: "key sk-proj_abcdefghijklmnop1234 rejected", want: "key [REDACTED] rejected"},
+		{text: "sk-short", want: "sk-short"},
+	}
+	for _, tt := range tests {
+		if got := r.Redact(tt.text); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	r.Configure(secret.Config{Patterns: []*regexp.Regexp{regexp.MustCompile(`\d{3}\.\d{3}\.\d{3}-\d{2}`)}})
+	if got, want := r.Redact("CPF 123.456.789-00, Bearer abc"), "CPF [REDACTED], Bearer abc"; got != want {
+		t.Errorf("Redact() after Configure = %q, want %q", got, want)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("REDACT_FIELDS", " cpf , ,birth_date")
+	t.Setenv("REDACT_PATTERNS", `\d{11}`)
+	cfg, err := secret.ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := secret.NewRedactor(cfg)
+	if !r.IsSensitive("CPF") || !r.IsSensitive("birth_date") || !r.IsSensitive("password") {
+		t.Error("the fields of REDACT_FIELDS or the default fields are not sensitive")
+	}
+	if got, want := r.Redact("phone 11987654321"), "phone [REDACTED]"; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+
+	t.Setenv("REDACT_PATTERNS", `(`)
+	if _, err := secret.ConfigFromEnv(); err == nil {
+		t.Error("an invalid pattern was accepted")
+	}
+}
+
+func TestSecret(t *testing.T) {
+	s := secret.New("s3cr3t-password")
+	t.Cleanup(func() { secret.Unregister("s3cr3t-password") })
+
+	if s.Reveal() != "s3cr3t-password" {
+		t.Errorf("Reveal() = %q", s.Reveal())
+	}
+	data, err := json.Marshal(map[string]any{"password": s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []string{fmt.Sprint(s), fmt.Sprintf("%#v", s), string(data)} {
+		if got == "" || strings.Contains(got, "s3cr3t") {
+			t.Errorf("the secret printed as %q", got)
+		}
+	}
+	if got := secret.Redact("login with s3cr3t-password"); got != "login with [REDACTED]" {
+		t.Errorf("the value of New was not registered: %q", got)
+	}
+	var zero secret.Secret
+	if !zero.IsZero() || zero.String() != "" {
+		t.Error("the zero secret is not empty")
+	}
+}
@@ -0,0 +1,53 @@
+// Package secret keeps credentials out of the logs, the error messages, the run reports and the recorded
+// traffic. Values wrapped in a Secret print as a placeholder, and the redactor masks their occurrences, the
+// values of sensitive fields and the configured patterns in any text.
+package secret
+
+import (
+	"encoding/json"
+	"log/slog"
+)
+
+// Redacted replaces the secret values
+const Redacted = "[REDACTED]"
+
+// Secret holds a sensitive value, such as a token or a password. It prints, marshals and logs as Redacted;
+// Reveal returns the value. Creating a Secret registers its value with the default redactor.
+type Secret struct {
+	value string
+}
+
+// New wraps the value and registers it, so its occurrences are redacted everywhere
+func New(value string) Secret {
+	Register(value)
+	return Secret{value: value}
+}
+
+// Reveal returns the value, to be sent to the API that needs it
+func (s Secret) Reveal() string {
+	return s.value
+}
+
+// IsZero reports whether the secret is empty
+func (s Secret) IsZero() bool {
+	return s.value == ""
+}
+
+func (s Secret) String() string {
+	if s.value == "" {
+		return ""
+	}
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return "secret.Secret{" + s.String() + "}"
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
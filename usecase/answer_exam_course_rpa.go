@@ -1,14 +1,16 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"time"
 
 	"github.com/luizhenriquees/go-http-rpa/ai"
+	"github.com/luizhenriquees/go-http-rpa/engine"
 	"github.com/luizhenriquees/go-http-rpa/entity"
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/report"
 )
 
 const (
@@ -29,21 +31,47 @@ type AnswerPayload struct {
 // AnswerExamRpa handles answering exam questions using AI
 type AnswerExamRpa struct {
 	assistant ai.ExamAssistant
-	waitTime  time.Duration
+	dryRun    *httprequest.DryRun
+	client    *httprequest.Client
+	login     *engine.LoginConfig
+	logger    engine.Logger
 }
 
 // NewAnswerExamRpa creates a new instance of AnswerExamRpa
 func NewAnswerExamRpa(assistant ai.ExamAssistant) *AnswerExamRpa {
 	return &AnswerExamRpa{
 		assistant: assistant,
-		waitTime:  2 * time.Second,
+		logger:    engine.NewLogger("Answer Exam RPA"),
 	}
 }
 
-// Execute processes the exam tasks
-func (a *AnswerExamRpa) Execute(input CourseInput) error {
-	fmt.Println("AnswerExamRpa initiating...")
-	courseList, err := fetchCourseStatus(&input)
+// SetDryRun answers the exams without starting nor submitting them, see httprequest.DryRun
+func (a *AnswerExamRpa) SetDryRun(dryRun *httprequest.DryRun) {
+	a.dryRun = dryRun
+}
+
+// SetClient sends the requests to the website with the client instead of the http_request default client
+func (a *AnswerExamRpa) SetClient(client *httprequest.Client) {
+	a.client = client
+}
+
+// SetLogin logs the rpa in before fetching the courses, see engine.NewLoginTask. The client needs a
+// session, see httprequest.Client.WithSession.
+func (a *AnswerExamRpa) SetLogin(cfg engine.LoginConfig) {
+	a.login = &cfg
+}
+
+// Execute processes the exam tasks. Cancelling the context stops the run between requests.
+func (a *AnswerExamRpa) Execute(ctx context.Context, input CourseInput) error {
+	a.logger.Info("AnswerExamRpa initiating...")
+	if a.dryRun != nil {
+		a.logger.Info("Dry run: exams will not be started nor submitted")
+		ctx = httprequest.WithDryRun(ctx, a.dryRun)
+	}
+	if err := logIn(ctx, a.client, a.login); err != nil {
+		return err
+	}
+	courseList, err := fetchCourseStatus(ctx, a.client, &input)
 	if err != nil {
 		return fmt.Errorf("failed to fetch courses status: %w", err)
 	}
@@ -51,61 +79,76 @@ func (a *AnswerExamRpa) Execute(input CourseInput) error {
 	examsList := getExamTasks(courseList)
 
 	for _, examID := range examsList {
-		fmt.Printf("Starting exam answering process for exam ID: %d\n", examID)
-		// Get exam details
-		examTask, err := a.getExamTask(input.BaseUrl, examID, input.Headers)
-		if err != nil {
-			return fmt.Errorf("failed to get exam task: %w", err)
+		if err := a.processExam(ctx, input, examID); err != nil {
+			return err
 		}
+	}
+	a.logger.Info("AnswerExamRpa finished!")
+	return nil
+}
 
-		if examTask.Type != taskTypeExam {
-			return fmt.Errorf("task %d is not an exam type", examID)
-		}
-		if examTask.Status == statusFinished {
-			return fmt.Errorf("task %d is already finished", examID)
-		}
+// processExam answers a single exam, recorded as the "exam[<id>]" step of the run report
+func (a *AnswerExamRpa) processExam(ctx context.Context, input CourseInput, examID int) (err error) {
+	ctx, done := report.Begin(ctx, fmt.Sprintf("exam[%d]", examID), "answer_exam")
+	defer func() { done(err) }()
+	ctx = engine.WithLogFields(ctx, "exam_id", examID)
+	logger := engine.ContextLogger(ctx, a.logger)
+	logger.Info("Starting exam answering process for exam ID: %d", examID)
+	// Get exam details
+	examTask, err := a.getExamTask(ctx, input.BaseUrl, examID, input.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to get exam task: %w", err)
+	}
 
-		fmt.Printf("Exam found: %s (ID: %d) with %d questions\n",
-			examTask.Name, examTask.ID, examTask.QuestionsCount)
+	if examTask.Type != taskTypeExam {
+		return fmt.Errorf("task %d is not an exam type", examID)
+	}
+	if examTask.Status == statusFinished {
+		return fmt.Errorf("task %d is already finished", examID)
+	}
 
-		// Start the exam if not already started
-		if examTask.Status != statusStarted {
-			examTask, err = a.startExam(input.BaseUrl, examID, input.Headers)
-			if err != nil {
-				return fmt.Errorf("failed to start exam: %w", err)
-			}
-			fmt.Println("Exam started successfully")
-		} else {
-			fmt.Println("Exam already started, continuing...")
-		}
+	logger.Info("Exam found: %s (ID: %d) with %d questions",
+		examTask.Name, examTask.ID, examTask.QuestionsCount)
 
-		// Process and answer questions
-		answers, err := a.processQuestions(examTask.Questions)
+	// Start the exam if not already started
+	if examTask.Status != statusStarted {
+		examTask, err = a.startExam(ctx, input.BaseUrl, examID, input.Headers)
 		if err != nil {
-			return fmt.Errorf("error processing questions: %w", err)
+			return fmt.Errorf("failed to start exam: %w", err)
 		}
+		logger.Info("Exam started successfully")
+	} else {
+		logger.Info("Exam already started, continuing...")
+	}
 
-		// Submit answers
-		if err := a.submitAnswers(input.BaseUrl, examID, answers, input.Headers); err != nil {
-			return fmt.Errorf("failed to submit answers: %w", err)
-		}
+	// Process and answer questions
+	answers, err := a.processQuestions(ctx, examTask.Questions)
+	if err != nil {
+		return fmt.Errorf("error processing questions: %w", err)
+	}
 
-		fmt.Println("Exam completed successfully!")
+	// Submit answers
+	if err := a.submitAnswers(ctx, input.BaseUrl, examID, answers, input.Headers); err != nil {
+		return fmt.Errorf("failed to submit answers: %w", err)
 	}
-	fmt.Println("AnswerExamRpa finished!")
+
+	logger.Info("Exam completed successfully!")
 	return nil
 }
 
 // getExamTask fetches the exam task details
-func (a *AnswerExamRpa) getExamTask(baseURL string, examID int, headers map[string]string) (*entity.Task, error) {
+func (a *AnswerExamRpa) getExamTask(ctx context.Context, baseURL string, examID int, headers map[string]string) (*entity.Task, error) {
 	url := baseURL + taskPath + strconv.Itoa(examID)
-	fmt.Printf("Fetching exam details from: %s\n", url)
+	engine.ContextLogger(ctx, a.logger).Info("Fetching exam details from: %s", url)
 
-	resp, err := httprequest.DoGet(url, headers)
+	resp, err := a.client.Get(ctx, url, headers)
 	if err != nil {
 		return nil, fmt.Errorf("error making GET request: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := httprequest.CheckStatus(resp); err != nil {
+		return nil, fmt.Errorf("error fetching exam task: %w", err)
+	}
 
 	var examTask entity.Task
 	if err := json.NewDecoder(resp.Body).Decode(&examTask); err != nil {
@@ -116,15 +159,18 @@ func (a *AnswerExamRpa) getExamTask(baseURL string, examID int, headers map[stri
 }
 
 // startExam initiates the exam
-func (a *AnswerExamRpa) startExam(baseURL string, examID int, headers map[string]string) (*entity.Task, error) {
+func (a *AnswerExamRpa) startExam(ctx context.Context, baseURL string, examID int, headers map[string]string) (*entity.Task, error) {
 	url := baseURL + taskPath + strconv.Itoa(examID) + startPath
-	fmt.Printf("Starting exam with request to: %s\n", url)
+	engine.ContextLogger(ctx, a.logger).Info("Starting exam with request to: %s", url)
 
-	resp, err := httprequest.DoPost(url, headers, []byte{})
+	resp, err := a.client.Post(ctx, url, headers, []byte{})
 	if err != nil {
 		return nil, fmt.Errorf("error making POST request to start exam: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := httprequest.CheckStatus(resp); err != nil {
+		return nil, fmt.Errorf("error starting exam: %w", err)
+	}
 
 	var examTask entity.Task
 	if err := json.NewDecoder(resp.Body).Decode(&examTask); err != nil {
@@ -135,36 +181,35 @@ func (a *AnswerExamRpa) startExam(baseURL string, examID int, headers map[string
 }
 
 // processQuestions processes each question and gets AI generated answers
-func (a *AnswerExamRpa) processQuestions(questions []entity.Question) ([]int, error) {
+func (a *AnswerExamRpa) processQuestions(ctx context.Context, questions []entity.Question) ([]int, error) {
 	answers := make([]int, len(questions))
+	logger := engine.ContextLogger(ctx, a.logger)
 
 	for i, question := range questions {
-		fmt.Printf("Processing question %d of %d\n", i+1, len(questions))
+		logger.Info("Processing question %d of %d", i+1, len(questions))
 
 		// If the question was already answered, use that answer
 		if question.Answered != nil {
-			fmt.Printf("Question %d was already answered with option %d\n", i+1, *question.Answered)
+			logger.Info("Question %d was already answered with option %d", i+1, *question.Answered)
 			answers[i] = *question.Answered
 			continue
 		}
 
 		// Get answer from AI
-		answerIndex, err := a.assistant.GetAnswerIndex(question)
+		answerIndex, err := a.assistant.GetAnswerIndex(ctx, question)
 		if err != nil {
 			return nil, fmt.Errorf("error getting answer from AI for question %d: %w", i+1, err)
 		}
 
-		fmt.Printf("AI selected answer %d for question %d\n", answerIndex, i+1)
+		logger.Info("AI selected answer %d for question %d", answerIndex, i+1)
 		answers[i] = answerIndex
-
-		time.Sleep(a.waitTime)
 	}
 
 	return answers, nil
 }
 
 // submitAnswers submits the answers to the exam
-func (a *AnswerExamRpa) submitAnswers(baseURL string, examID int, answers []int, headers map[string]string) error {
+func (a *AnswerExamRpa) submitAnswers(ctx context.Context, baseURL string, examID int, answers []int, headers map[string]string) error {
 	// First submit answers
 	answerURL := baseURL + taskPath + strconv.Itoa(examID) + answerPath
 	payload := AnswerPayload{
@@ -176,25 +221,65 @@ func (a *AnswerExamRpa) submitAnswers(baseURL string, examID int, answers []int,
 		return fmt.Errorf("error marshalling answer payload: %w", err)
 	}
 
-	fmt.Printf("Submitting answers to: %s\n", answerURL)
-	fmt.Printf("Answer payload: %s\n", string(payloadJSON))
+	logger := engine.ContextLogger(ctx, a.logger)
+	logger.Info("Submitting answers to: %s", answerURL)
+	logger.Debug("Submitting %d answers: %v", len(answers), answers)
 
-	_, err = httprequest.DoPost(answerURL, headers, payloadJSON)
-	if err != nil {
+	if err := postAndCheck(ctx, a.client, answerURL, headers, payloadJSON); err != nil {
 		return fmt.Errorf("error submitting answers: %w", err)
 	}
 
 	finishURL := baseURL + taskPath + strconv.Itoa(examID) + finishPath
-	fmt.Printf("Finishing exam with request to: %s\n", finishURL)
+	logger.Info("Finishing exam with request to: %s", finishURL)
 
-	_, err = httprequest.DoPost(finishURL, headers, payloadJSON)
+	finished, err := postForTask(ctx, a.client, finishURL, headers, payloadJSON)
 	if err != nil {
 		return fmt.Errorf("error finishing exam: %w", err)
 	}
-
+	recordAnswers(ctx, "exam", examID, answers, finished)
 	return nil
 }
 
+// postForTask sends a POST answered with the task, failing on unexpected statuses. The task is nil when
+// the response body is empty or is not a task, since the request itself succeeded.
+func postForTask(ctx context.Context, client *httprequest.Client, url string, headers map[string]string, body []byte) (*entity.Task, error) {
+	resp, err := client.Post(ctx, url, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := httprequest.CheckStatus(resp); err != nil {
+		return nil, err
+	}
+	var task entity.Task
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		return nil, nil
+	}
+	return &task, nil
+}
+
+// recordAnswers adds the submitted answers to the run report, corrected with the questions of the
+// finished task when the API returned them
+func recordAnswers(ctx context.Context, kind string, taskID int, answers []int, finished *entity.Task) {
+	for i, option := range answers {
+		answer := report.Answer{Kind: kind, ID: strconv.Itoa(taskID), Question: i, Option: option}
+		if finished != nil && i < len(finished.Questions) {
+			answer = answer.Corrected(finished.Questions[i].Correct)
+		}
+		report.RecordAnswer(ctx, answer)
+	}
+}
+
+// postAndCheck sends a POST whose response body is not needed, failing on unexpected statuses
+func postAndCheck(ctx context.Context, client *httprequest.Client, url string, headers map[string]string, body []byte) error {
+	resp, err := client.Post(ctx, url, headers, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return httprequest.CheckStatus(resp)
+}
+
 // getExamTasks extracts all tasks of the type "exam" from the course list
 func getExamTasks(courseList *entity.CoursesList) []int {
 	if courseList == nil {
@@ -206,12 +291,12 @@ func getExamTasks(courseList *entity.CoursesList) []int {
 			for _, task := range module.Tasks {
 				if task.Type == taskTypeExam && task.Status != statusFinished {
 					examTasks = append(examTasks, task.ID)
-					fmt.Printf("Found exam task: %s (ID: %d) in course %d, module %d\n",
+					coursesLogger.Info("Found exam task: %s (ID: %d) in course %d, module %d",
 						task.Name, task.ID, course.ID, module.ID)
 				}
 			}
 		}
 	}
-	fmt.Printf("Total exam tasks found: %d\n", len(examTasks))
+	coursesLogger.Info("Total exam tasks found: %d", len(examTasks))
 	return examTasks
 }
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -24,12 +25,14 @@ type QuizInput struct {
 
 type AnswerQuizRpa struct {
 	logger engine.Logger
+	dryRun *httprequest.DryRun
+	client *httprequest.Client
 }
 
 // NewAnswerQuizRpa this RPA is deprecated
 func NewAnswerQuizRpa() *AnswerQuizRpa {
 	return &AnswerQuizRpa{
-		logger: &engine.DefaultLogger{},
+		logger: engine.NewLogger("Answer Quiz RPA"),
 	}
 }
 
@@ -37,23 +40,36 @@ func (c *AnswerQuizRpa) SetLogger(logger engine.Logger) {
 	c.logger = logger
 }
 
-func (c *AnswerQuizRpa) Execute(input QuizInput) error {
+// SetDryRun answers the quizzes without starting nor submitting them, see httprequest.DryRun
+func (c *AnswerQuizRpa) SetDryRun(dryRun *httprequest.DryRun) {
+	c.dryRun = dryRun
+}
+
+// SetClient sends the requests with the client instead of the http_request default client
+func (c *AnswerQuizRpa) SetClient(client *httprequest.Client) {
+	c.client = client
+}
+
+func (c *AnswerQuizRpa) Execute(ctx context.Context, input QuizInput) error {
+	if c.dryRun != nil {
+		c.logger.Info("Dry run: quizzes will not be started nor submitted")
+		ctx = httprequest.WithDryRun(ctx, c.dryRun)
+	}
 	if len(input.QuizesId) == 0 {
-		if err := c.fetchAllAvailableQuizzes(&input); err != nil {
-			c.logger.Error("Error fetching all quizzes available", err)
+		if err := c.fetchAllAvailableQuizzes(ctx, &input); err != nil {
+			c.logger.Error("Error fetching all quizzes available: %v", err)
 			return err
 		}
 	}
 	c.logger.Info("Initiating quiz answering for ids: %v", input.QuizesId)
 	for _, quizID := range input.QuizesId {
-		quizData, err := c.startQuiz(input.BaseUrl, quizID, input.Headers)
+		quizData, err := c.startQuiz(ctx, input.BaseUrl, quizID, input.Headers)
 		if err != nil {
-			c.logger.Error("Error starting quiz", err, "quizID", quizID)
+			c.logger.Error("Error starting quiz %d: %v", quizID, err)
 			return err
 		}
-		time.Sleep(DefaultWaitTime)
-		if err := c.answerQuizQuestions(input.BaseUrl, quizID, quizData, input.Headers); err != nil {
-			c.logger.Error("Error answering quiz questions", err, "quizID", quizID)
+		if err := c.answerQuizQuestions(ctx, input.BaseUrl, quizID, quizData, input.Headers); err != nil {
+			c.logger.Error("Error answering questions of quiz %d: %v", quizID, err)
 			return err
 		}
 	}
@@ -68,10 +84,10 @@ func (c *AnswerQuizRpa) buildQuizURL(baseURL string, quizID int, action string)
 	return url
 }
 
-func (c *AnswerQuizRpa) startQuiz(baseURL string, quizID int, headers map[string]string) (*entity.QuizData, error) {
+func (c *AnswerQuizRpa) startQuiz(ctx context.Context, baseURL string, quizID int, headers map[string]string) (*entity.QuizData, error) {
 	startURL := c.buildQuizURL(baseURL, quizID, "start")
 	c.logger.Info("Starting quiz: POST %s", startURL)
-	quizData, err := c.doPostRequest(startURL, headers, nil)
+	quizData, err := c.doPostRequest(ctx, startURL, headers, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -79,12 +95,15 @@ func (c *AnswerQuizRpa) startQuiz(baseURL string, quizID int, headers map[string
 	return quizData, nil
 }
 
-func (c *AnswerQuizRpa) doPostRequest(url string, headers map[string]string, body []byte) (*entity.QuizData, error) {
-	resp, err := httprequest.DoPost(url, headers, body)
+func (c *AnswerQuizRpa) doPostRequest(ctx context.Context, url string, headers map[string]string, body []byte) (*entity.QuizData, error) {
+	resp, err := c.client.Post(ctx, url, headers, body)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP post request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := httprequest.CheckStatus(resp); err != nil {
+		return nil, err
+	}
 	var responseData entity.QuizData
 	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -92,20 +111,25 @@ func (c *AnswerQuizRpa) doPostRequest(url string, headers map[string]string, bod
 	return &responseData, nil
 }
 
-func (c *AnswerQuizRpa) answerQuizQuestions(baseURL string, quizID int, quizData *entity.QuizData, headers map[string]string) error {
+func (c *AnswerQuizRpa) answerQuizQuestions(ctx context.Context, baseURL string, quizID int, quizData *entity.QuizData, headers map[string]string) error {
 	answerURL := c.buildQuizURL(baseURL, quizID, "answer")
 	c.logger.Info("Answering questions at: %s", answerURL)
 	for index, question := range quizData.Questions {
 		payload := c.createAnswerPayload(len(question.Options), index)
 		c.logQuestionInfo(question, payload)
-		respAnswer, err := c.doPostRequest(answerURL, headers, []byte(payload))
+		respAnswer, err := c.doPostRequest(ctx, answerURL, headers, []byte(payload))
 		if err != nil {
 			return fmt.Errorf("failed to submit answer for question %d: %w", index, err)
 		}
-		time.Sleep(DefaultWaitTime)
+		if index >= len(respAnswer.Questions) {
+			if httprequest.IsDryRun(ctx) {
+				c.logger.Info("Question %d result - no correction (dry run)", index)
+				continue
+			}
+			return fmt.Errorf("the answer response holds no result for question %d", index)
+		}
 		c.logger.Info("Question %d result - Correct: %d, Answered: %d",
 			index, respAnswer.Questions[index].Correct, respAnswer.Questions[index].Answered)
-		c.logger.Info("=====================================================")
 	}
 	return nil
 }
@@ -122,15 +146,18 @@ func (c *AnswerQuizRpa) createAnswerPayload(optionsCount int, questionIndex int)
 	return fmt.Sprintf(`{"answer":%d,"question_index":%d}`, answerIndex, questionIndex)
 }
 
-func (c *AnswerQuizRpa) fetchAllAvailableQuizzes(input *QuizInput) error {
+func (c *AnswerQuizRpa) fetchAllAvailableQuizzes(ctx context.Context, input *QuizInput) error {
 	c.logger.Info("No specific quiz ID provided. Fetching all available quizzes.")
 	quizzesURL := input.BaseUrl + quizPath
 	c.logger.Info("GET request to: %s", quizzesURL)
-	resp, err := httprequest.DoGet(quizzesURL, input.Headers)
+	resp, err := c.client.Get(ctx, quizzesURL, input.Headers)
 	if err != nil {
 		return fmt.Errorf("failed to fetch quizzes: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := httprequest.CheckStatus(resp); err != nil {
+		return fmt.Errorf("failed to fetch quizzes: %w", err)
+	}
 	var quizList entity.QuizList
 	if err := json.NewDecoder(resp.Body).Decode(&quizList); err != nil {
 		return fmt.Errorf("failed to decode quiz list: %w", err)
@@ -1,14 +1,17 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
 	"strconv"
 	"time"
 
+	"github.com/luizhenriquees/go-http-rpa/engine"
 	"github.com/luizhenriquees/go-http-rpa/entity"
 	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/report"
 )
 
 const (
@@ -21,8 +24,7 @@ const (
 	taskTypeTest = "test"
 
 	// Task status
-	statusFinished  = "finished"
-	DefaultWaitTime = 2 * time.Second
+	statusFinished = "finished"
 )
 
 type CourseInput struct {
@@ -31,16 +33,53 @@ type CourseInput struct {
 	Headers   map[string]string
 }
 
+// coursesLogger logs the course list operations shared by the course RPAs
+var coursesLogger = engine.NewLogger("Courses")
+
 type WatchCourseRpa struct {
-	waitTime time.Duration
+	journal *engine.Journal
+	dryRun  *httprequest.DryRun
+	client  *httprequest.Client
+	login   *engine.LoginConfig
+	logger  engine.Logger
 }
 
 type WatchCourseOption func(*WatchCourseRpa)
 
+// WithJournal records every finished task in the journal, so a resumed run skips them
+func WithJournal(journal *engine.Journal) WatchCourseOption {
+	return func(w *WatchCourseRpa) {
+		w.journal = journal
+	}
+}
+
+// WithDryRun walks the courses without starting nor finishing their tasks, see httprequest.DryRun.
+// A dry run neither reads nor writes the journal.
+func WithDryRun(dryRun *httprequest.DryRun) WatchCourseOption {
+	return func(w *WatchCourseRpa) {
+		w.dryRun = dryRun
+	}
+}
+
+// WithClient sends the requests to the website with the client instead of the http_request default client
+func WithClient(client *httprequest.Client) WatchCourseOption {
+	return func(w *WatchCourseRpa) {
+		w.client = client
+	}
+}
+
+// WithLogin logs the rpa in before fetching the courses, see engine.NewLoginTask. The client needs a
+// session, see httprequest.Client.WithSession.
+func WithLogin(cfg engine.LoginConfig) WatchCourseOption {
+	return func(w *WatchCourseRpa) {
+		w.login = &cfg
+	}
+}
+
 // NewWatchCourseRpa this RPA is deprecated
 func NewWatchCourseRpa(opts ...WatchCourseOption) *WatchCourseRpa {
 	rpa := &WatchCourseRpa{
-		waitTime: DefaultWaitTime,
+		logger: engine.NewLogger("Watch Course RPA"),
 	}
 	for _, opt := range opts {
 		opt(rpa)
@@ -48,73 +87,125 @@ func NewWatchCourseRpa(opts ...WatchCourseOption) *WatchCourseRpa {
 	return rpa
 }
 
-func (w *WatchCourseRpa) Execute(input CourseInput) error {
-	courseList, err := fetchCourseStatus(&input)
+// Execute watches every selected course. Cancelling the context stops the run between requests.
+func (w *WatchCourseRpa) Execute(ctx context.Context, input CourseInput) error {
+	if w.dryRun != nil {
+		w.logger.Info("Dry run: tasks will not be started nor finished")
+		ctx = httprequest.WithDryRun(ctx, w.dryRun)
+	}
+	if err := logIn(ctx, w.client, w.login); err != nil {
+		return err
+	}
+	courseList, err := fetchCourseStatus(ctx, w.client, &input)
 	if err != nil {
 		return fmt.Errorf("failed to fetch courses status: %w", err)
 	}
 	filterCoursesBasedOnInput(input, courseList)
 	for _, course := range courseList.Courses {
-		if err := w.processCourse(input, course); err != nil {
+		if err := w.processCourse(ctx, input, course); err != nil {
 			return fmt.Errorf("error processing course %d: %w", course.ID, err)
 		}
 	}
+	if journal := w.activeJournal(); journal != nil {
+		if err := journal.Finish(); err != nil {
+			w.logger.Warn("Could not remove journal: %v", err)
+		}
+	}
 	return nil
 }
 
-func (w *WatchCourseRpa) processCourse(input CourseInput, course entity.Course) error {
-	fmt.Printf("Watching Course ID: %d\n", course.ID)
+func (w *WatchCourseRpa) processCourse(ctx context.Context, input CourseInput, course entity.Course) (err error) {
+	ctx, done := report.Begin(ctx, fmt.Sprintf("course[%d]", course.ID), "watch_course")
+	defer func() { done(err) }()
+	ctx = engine.WithLogFields(ctx, "course_id", course.ID)
+	engine.ContextLogger(ctx, w.logger).Info("Watching course %d", course.ID)
 	for _, module := range course.Modules {
-		if err := w.processModule(input, course.ID, module); err != nil {
+		if err := w.processModule(ctx, input, course.ID, module); err != nil {
 			return fmt.Errorf("error processing module %d: %w", module.ID, err)
 		}
 	}
 	return nil
 }
 
-func (w *WatchCourseRpa) processModule(input CourseInput, courseID int, module entity.Module) error {
-	fmt.Printf("Watching Module ID: %d\n", module.ID)
+func (w *WatchCourseRpa) processModule(ctx context.Context, input CourseInput, courseID int, module entity.Module) error {
+	ctx = engine.WithLogFields(ctx, "module_id", module.ID)
+	logger := engine.ContextLogger(ctx, w.logger)
+	logger.Info("Watching module %d", module.ID)
 	for _, task := range module.Tasks {
 		if task.Type == taskTypeExam {
-			fmt.Printf("[Course %d] | [Module %d] - Task %d is an exam! Stopping...\n",
-				courseID, module.ID, task.ID)
+			logger.Info("Task %d is an exam! Stopping...", task.ID)
 			break
 		}
-		if err := w.processTask(input, courseID, module.ID, task); err != nil {
+		if err := w.processTask(ctx, input, courseID, module.ID, task); err != nil {
 			return fmt.Errorf("error processing task %d: %w", task.ID, err)
 		}
-		time.Sleep(w.waitTime)
 	}
 	return nil
 }
 
-func (w *WatchCourseRpa) processTask(input CourseInput, courseID, moduleID int, task entity.Task) error {
-	startedTask, err := w.startTask(input, courseID, moduleID, task)
+func (w *WatchCourseRpa) processTask(ctx context.Context, input CourseInput, courseID, moduleID int, task entity.Task) (err error) {
+	ctx = engine.WithLogFields(ctx, "task_id", task.ID)
+	step := fmt.Sprintf("course[%d]/task[%d]", courseID, task.ID)
+	journal := w.activeJournal()
+	if journal != nil && journal.IsCompleted(step) {
+		engine.ContextLogger(ctx, w.logger).Info("Task %d finished in a previous run, skipping", task.ID)
+		return nil
+	}
+	ctx, done := report.Begin(ctx, step, "watch_task")
+	defer func() { done(err) }()
+	if err := w.doProcessTask(ctx, input, courseID, moduleID, task); err != nil {
+		return err
+	}
+	if journal != nil {
+		return journal.MarkCompleted(step)
+	}
+	return nil
+}
+
+// activeJournal returns the journal of the run, nil during a dry run
+func (w *WatchCourseRpa) activeJournal() *engine.Journal {
+	if w.dryRun != nil {
+		return nil
+	}
+	return w.journal
+}
+
+func (w *WatchCourseRpa) doProcessTask(ctx context.Context, input CourseInput, courseID, moduleID int, task entity.Task) error {
+	startedTask, err := w.startTask(ctx, input, courseID, moduleID, task)
 	if err != nil {
 		return err
 	}
-	time.Sleep(w.waitTime)
 	var questionAnsweredBody []byte
+	var answers []int
 	if w.isTaskATest(startedTask) {
-		fmt.Printf("Task %d is a single test! Building answer...\n", task.ID)
-		answerJSON := w.buildCourseTestAnswer(len(startedTask.Questions[0].Options))
+		engine.ContextLogger(ctx, w.logger).Info("Task %d is a single test! Building answer...", task.ID)
+		answerJSON, answerIndex := w.buildCourseTestAnswer(ctx, len(startedTask.Questions[0].Options))
 		questionAnsweredBody = []byte(answerJSON)
+		answers = []int{answerIndex}
 	}
-	return w.finishTask(input, courseID, moduleID, task.ID, questionAnsweredBody)
+	finished, err := w.finishTask(ctx, input, courseID, moduleID, task.ID, questionAnsweredBody)
+	if err != nil {
+		return err
+	}
+	recordAnswers(ctx, "test", task.ID, answers, finished)
+	return nil
 }
 
 func (w *WatchCourseRpa) isTaskATest(startedTask *entity.Task) bool {
 	return startedTask.Type == taskTypeTest && startedTask.QuestionsCount == 1 && startedTask.Status != statusFinished
 }
 
-func (w *WatchCourseRpa) startTask(input CourseInput, courseID, moduleID int, task entity.Task) (*entity.Task, error) {
+func (w *WatchCourseRpa) startTask(ctx context.Context, input CourseInput, courseID, moduleID int, task entity.Task) (*entity.Task, error) {
 	urlStartTask := input.BaseUrl + taskPath + strconv.Itoa(task.ID) + "/start"
-	respStartTask, err := httprequest.DoPost(urlStartTask, input.Headers, []byte(""))
+	respStartTask, err := w.client.Post(ctx, urlStartTask, input.Headers, []byte(""))
 	if err != nil {
 		return nil, fmt.Errorf("error starting task %d: %w", task.ID, err)
 	}
 	defer respStartTask.Body.Close()
-	fmt.Printf("[Course %d] | [Module %d] - Task %d started!\n", courseID, moduleID, task.ID)
+	if err := httprequest.CheckStatus(respStartTask); err != nil {
+		return nil, fmt.Errorf("error starting task %d: %w", task.ID, err)
+	}
+	engine.ContextLogger(ctx, w.logger).Info("Task %d started!", task.ID)
 	var startedTask entity.Task
 	if err := json.NewDecoder(respStartTask.Body).Decode(&startedTask); err != nil {
 		return nil, fmt.Errorf("error parsing started task: %w", err)
@@ -122,47 +213,68 @@ func (w *WatchCourseRpa) startTask(input CourseInput, courseID, moduleID int, ta
 	return &startedTask, nil
 }
 
-func (w *WatchCourseRpa) finishTask(input CourseInput, courseID, moduleID, taskID int, answerBody []byte) error {
+// finishTask finishes the task and returns it as answered by the API, nil when the response is not a task
+func (w *WatchCourseRpa) finishTask(ctx context.Context, input CourseInput, courseID, moduleID, taskID int, answerBody []byte) (*entity.Task, error) {
 	urlFinishTask := input.BaseUrl + taskPath + strconv.Itoa(taskID) + "/finish"
-	_, err := httprequest.DoPost(urlFinishTask, input.Headers, answerBody)
+	finished, err := postForTask(ctx, w.client, urlFinishTask, input.Headers, answerBody)
 	if err != nil {
-		return fmt.Errorf("error finishing task %d: %w", taskID, err)
+		return nil, fmt.Errorf("error finishing task %d: %w", taskID, err)
 	}
-	fmt.Printf("[Course %d] | [Module %d] - Task %d finished!\n", courseID, moduleID, taskID)
-	return nil
+	engine.ContextLogger(ctx, w.logger).Info("Task %d finished!", taskID)
+	return finished, nil
 }
 
-func (w *WatchCourseRpa) buildCourseTestAnswer(optionsLength int) string {
+func (w *WatchCourseRpa) buildCourseTestAnswer(ctx context.Context, optionsLength int) (string, int) {
 	source := rand.NewSource(time.Now().UnixNano())
 	random := rand.New(source)
 	randAnswerIndex := random.Intn(optionsLength)
-	fmt.Printf("Answer index chosen: %d\n", randAnswerIndex)
-	return fmt.Sprintf(`{"answers":[%d]}`, randAnswerIndex)
+	engine.ContextLogger(ctx, w.logger).Info("Answer index chosen: %d", randAnswerIndex)
+	return fmt.Sprintf(`{"answers":[%d]}`, randAnswerIndex), randAnswerIndex
+}
+
+// logIn runs the login task of the config, if any, recorded as the "login" step of the run report
+func logIn(ctx context.Context, client *httprequest.Client, cfg *engine.LoginConfig) (err error) {
+	if cfg == nil {
+		return nil
+	}
+	ctx, done := report.Begin(ctx, "login", "login")
+	defer func() { done(err) }()
+	task := engine.NewLoginTask("login", *cfg, nil, nil, engine.WithClient(client))
+	if err := task.Validate(); err != nil {
+		return fmt.Errorf("validation failed for task login: %w", err)
+	}
+	if err := task.Execute(ctx); err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	return nil
 }
 
-func fetchCourseStatus(input *CourseInput) (*entity.CoursesList, error) {
+func fetchCourseStatus(ctx context.Context, client *httprequest.Client, input *CourseInput) (*entity.CoursesList, error) {
 	urlGetCourses := input.BaseUrl + statusPath
-	fmt.Println("GET to:", urlGetCourses)
-	resp, err := httprequest.DoGet(urlGetCourses, input.Headers)
+	coursesLogger.Info("GET to: %s", urlGetCourses)
+	resp, err := client.Get(ctx, urlGetCourses, input.Headers)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching courses list: %w", err)
 	}
 	defer resp.Body.Close()
+	if err := httprequest.CheckStatus(resp); err != nil {
+		return nil, fmt.Errorf("error fetching courses list: %w", err)
+	}
 	var responseData entity.CoursesList
-	fmt.Println("Courses fetched, extracting data...")
+	coursesLogger.Debug("Courses fetched, extracting data...")
 	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
 		return nil, fmt.Errorf("error decoding courses list: %w", err)
 	}
-	fmt.Printf("%d courses extracted...\n", len(responseData.Courses))
+	coursesLogger.Info("%d courses extracted...", len(responseData.Courses))
 	return &responseData, nil
 }
 
 func filterCoursesBasedOnInput(input CourseInput, courseList *entity.CoursesList) {
 	if len(input.CourseIDs) == 0 {
-		fmt.Println("No specific course ID provided. All available courses will be watched.")
+		coursesLogger.Info("No specific course ID provided. All available courses will be watched.")
 		return
 	}
-	fmt.Printf("Filtering course IDs: %v\n", input.CourseIDs)
+	coursesLogger.Info("Filtering course IDs: %v", input.CourseIDs)
 	idMap := make(map[int]bool)
 	for _, id := range input.CourseIDs {
 		idMap[id] = true
@@ -174,5 +286,5 @@ func filterCoursesBasedOnInput(input CourseInput, courseList *entity.CoursesList
 		}
 	}
 	courseList.Courses = filteredCourses
-	fmt.Printf("%d courses remaining after filter...\n", len(courseList.Courses))
+	coursesLogger.Info("%d courses remaining after filter...", len(courseList.Courses))
 }
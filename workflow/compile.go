@@ -0,0 +1,216 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/luizhenriquees/go-http-rpa/engine"
+	httprequest "github.com/luizhenriquees/go-http-rpa/http_request"
+	"github.com/luizhenriquees/go-http-rpa/secret"
+)
+
+// Compile builds an engine.Rpa from the workflow definition.
+// The base URL is exposed to templates as the engine.ParamBaseURL parameter. The values of the sensitive
+// headers, such as X-Authorization, are registered as secrets.
+func Compile(def *Definition) (*engine.Rpa, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	headers := make(httprequest.Headers, len(def.Headers))
+	for key, value := range def.Headers {
+		if secret.IsSensitive(key) {
+			headers.SetSecret(key, secret.New(value))
+			continue
+		}
+		headers[key] = value
+	}
+
+	rpa := engine.NewRpa(def.Name, def.BaseURL, headers)
+	params := engine.NewParameters(def.Params)
+	params.Put(engine.ParamBaseURL, def.BaseURL)
+	rpa.SetParams(params)
+
+	for _, spec := range def.Tasks {
+		task, err := buildTask(spec, headers, params)
+		if err != nil {
+			return nil, err
+		}
+		rpa.AddTask(task)
+	}
+	return rpa, nil
+}
+
+func buildTask(spec TaskSpec, headers httprequest.Headers, params *engine.Parameters) (engine.Task, error) {
+	switch {
+	case spec.HTTP != nil:
+		return buildHTTPTask(spec.Name, spec.HTTP, headers, params)
+	case spec.Pipeline != nil:
+		tasks := make([]engine.Task, 0, len(spec.Pipeline))
+		for _, child := range spec.Pipeline {
+			task, err := buildTask(child, headers, params)
+			if err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, task)
+		}
+		pipeline := engine.NewPipelinedTasks(spec.Name, tasks)
+		if spec.Scoped || len(spec.Publish) > 0 {
+			pipeline.Scoped(params, spec.Publish...)
+		}
+		return pipeline, nil
+	case spec.Iterate != nil:
+		task, err := buildTask(spec.Iterate.Task, headers, params)
+		if err != nil {
+			return nil, err
+		}
+		var opts []engine.IterableOption
+		if spec.Iterate.OnError == onErrorContinue {
+			opts = append(opts, engine.WithErrorMode(engine.CollectAll))
+		}
+		if spec.Iterate.MaxFailures > 0 {
+			opts = append(opts, engine.WithMaxFailures(spec.Iterate.MaxFailures))
+		}
+		return engine.NewIterableTask[any](spec.Name, spec.Iterate.Over, task, params, opts...), nil
+	case spec.If != nil:
+		return buildIfTask(spec.Name, spec.If, headers, params)
+	case spec.Switch != nil:
+		return buildSwitchTask(spec.Name, spec.Switch, headers, params)
+	}
+	return nil, fmt.Errorf("task %q: unknown task kind", spec.Name)
+}
+
+func buildIfTask(name string, spec *IfSpec, headers httprequest.Headers, params *engine.Parameters) (engine.Task, error) {
+	predicate, err := engine.TemplatePredicate(spec.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("task %q: %w", name, err)
+	}
+	then, err := buildTask(spec.Then, headers, params)
+	if err != nil {
+		return nil, err
+	}
+	task := engine.NewIfTask(name, params, predicate, then)
+	if spec.Else != nil {
+		otherwise, err := buildTask(*spec.Else, headers, params)
+		if err != nil {
+			return nil, err
+		}
+		task.Else(otherwise)
+	}
+	return task, nil
+}
+
+func buildSwitchTask(name string, spec *SwitchSpec, headers httprequest.Headers, params *engine.Parameters) (engine.Task, error) {
+	selector, err := engine.TemplateSelector(spec.Value)
+	if err != nil {
+		return nil, fmt.Errorf("task %q: %w", name, err)
+	}
+	task := engine.NewSwitchTask(name, params, selector)
+	for value, caseSpec := range spec.Cases {
+		caseTask, err := buildTask(caseSpec, headers, params)
+		if err != nil {
+			return nil, err
+		}
+		task.Case(value, caseTask)
+	}
+	if spec.Default != nil {
+		defaultTask, err := buildTask(*spec.Default, headers, params)
+		if err != nil {
+			return nil, err
+		}
+		task.Default(defaultTask)
+	}
+	return task, nil
+}
+
+func buildHTTPTask(name string, spec *HTTPSpec, defaults httprequest.Headers, params *engine.Parameters) (*engine.HTTPTask, error) {
+	method := httprequest.HTTPMethod(strings.ToUpper(spec.Method))
+	if method == "" {
+		method = httprequest.GET
+	}
+	if !method.Valid() {
+		return nil, fmt.Errorf("task %q: unsupported HTTP method %q", name, spec.Method)
+	}
+
+	rules := make([]engine.ExtractRule, 0, len(spec.Extract))
+	for _, extract := range spec.Extract {
+		rule, err := buildExtractRule(name, extract)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	headers := make(httprequest.Headers, len(defaults)+len(spec.Headers))
+	for key, value := range defaults {
+		headers[key] = value
+	}
+
+	options := []engine.Option{
+		engine.WithRequiredParams(spec.RequiredParams),
+		engine.WithURLTemplate(spec.URL),
+	}
+	for key, value := range spec.Headers {
+		options = append(options, engine.WithHeaderTemplate(key, value))
+	}
+	for key, value := range spec.Query {
+		options = append(options, engine.WithQueryParam(key, value))
+	}
+	if spec.Body != "" {
+		options = append(options, engine.WithBodyTemplate(spec.Body))
+	}
+	if len(spec.Form) > 0 {
+		options = append(options, engine.WithFormBody(spec.Form))
+	}
+	if len(spec.ExpectStatus) > 0 {
+		options = append(options, engine.WithExpectedStatus(spec.ExpectStatus...))
+	}
+	if len(rules) > 0 {
+		options = append(options, engine.WithExtractRules(rules...))
+	}
+
+	return engine.NewHTTPTask(name, method, headers, params, options...), nil
+}
+
+func buildExtractRule(taskName string, spec ExtractSpec) (engine.ExtractRule, error) {
+	switch spec.Type {
+	case "", "any":
+		return extractRule[any](taskName, spec)
+	case "list":
+		return extractRule[[]any](taskName, spec)
+	case "string":
+		return extractRule[string](taskName, spec)
+	case "int":
+		return extractRule[int](taskName, spec)
+	case "float":
+		return extractRule[float64](taskName, spec)
+	case "bool":
+		return extractRule[bool](taskName, spec)
+	case "[]string":
+		return extractRule[[]string](taskName, spec)
+	case "[]int":
+		return extractRule[[]int](taskName, spec)
+	}
+	return engine.ExtractRule{}, fmt.Errorf("task %q: unsupported extract type %q", taskName, spec.Type)
+}
+
+// extractRule builds a typed rule. Defaults are converted to T through JSON, since
+// a YAML "default: 0" is decoded as an int even when the rule extracts a float.
+func extractRule[T any](taskName string, spec ExtractSpec) (engine.ExtractRule, error) {
+	var opts []engine.ExtractOption
+	if spec.Required {
+		opts = append(opts, engine.Required())
+	}
+	if spec.Default != nil {
+		raw, err := json.Marshal(spec.Default)
+		if err != nil {
+			return engine.ExtractRule{}, fmt.Errorf("task %q: invalid default for %q: %w", taskName, spec.Path, err)
+		}
+		var value T
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return engine.ExtractRule{}, fmt.Errorf("task %q: default for %q is not a %s: %w", taskName, spec.Path, spec.Type, err)
+		}
+		opts = append(opts, engine.Default(value))
+	}
+	return engine.Extract[T](spec.Path, spec.Into, opts...), nil
+}
@@ -0,0 +1,263 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition describes a complete rpa declared in a workflow file
+type Definition struct {
+	Name    string            `yaml:"name" json:"name"`
+	BaseURL string            `yaml:"base_url" json:"base_url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Params  map[string]any    `yaml:"params" json:"params"`
+	Tasks   []TaskSpec        `yaml:"tasks" json:"tasks"`
+}
+
+// TaskSpec describes a single task. Exactly one of HTTP, Pipeline, Iterate, If or Switch must be set.
+type TaskSpec struct {
+	Name     string       `yaml:"name" json:"name"`
+	HTTP     *HTTPSpec    `yaml:"http" json:"http"`
+	Pipeline []TaskSpec   `yaml:"pipeline" json:"pipeline"`
+	Iterate  *IterateSpec `yaml:"iterate" json:"iterate"`
+	If       *IfSpec      `yaml:"if" json:"if"`
+	Switch   *SwitchSpec  `yaml:"switch" json:"switch"`
+	// Scoped runs a pipeline in its own parameter scope, discarded when it ends
+	Scoped bool `yaml:"scoped" json:"scoped"`
+	// Publish lists the keys a scoped pipeline copies to the parent scope. It implies Scoped.
+	Publish []string `yaml:"publish" json:"publish"`
+}
+
+// HTTPSpec describes an HTTP request. URL, header, query and form values and body are Go templates rendered
+// from the parameters. Form sends an application/x-www-form-urlencoded body and cannot be combined with Body.
+type HTTPSpec struct {
+	Method         string            `yaml:"method" json:"method"`
+	URL            string            `yaml:"url" json:"url"`
+	Headers        map[string]string `yaml:"headers" json:"headers"`
+	Query          map[string]string `yaml:"query" json:"query"`
+	Body           string            `yaml:"body" json:"body"`
+	Form           map[string]string `yaml:"form" json:"form"`
+	RequiredParams []string          `yaml:"required_params" json:"required_params"`
+	ExpectStatus   []int             `yaml:"expect_status" json:"expect_status"`
+	Extract        []ExtractSpec     `yaml:"extract" json:"extract"`
+}
+
+// IterateSpec describes a task executed once for every element stored under the Over parameter.
+// OnError is "fail_fast" (the default) or "continue"; MaxFailures implies "continue" and stops the
+// iteration once that many elements failed.
+type IterateSpec struct {
+	Over        string   `yaml:"over" json:"over"`
+	Task        TaskSpec `yaml:"task" json:"task"`
+	OnError     string   `yaml:"on_error" json:"on_error"`
+	MaxFailures int      `yaml:"max_failures" json:"max_failures"`
+}
+
+// IfSpec describes a task executed only when the Condition template renders to a true value.
+// Empty output, "false", "0" and missing keys are false.
+type IfSpec struct {
+	Condition string    `yaml:"condition" json:"condition"`
+	Then      TaskSpec  `yaml:"then" json:"then"`
+	Else      *TaskSpec `yaml:"else" json:"else"`
+}
+
+// SwitchSpec describes a choice among tasks keyed by the rendered Value template
+type SwitchSpec struct {
+	Value   string              `yaml:"value" json:"value"`
+	Cases   map[string]TaskSpec `yaml:"cases" json:"cases"`
+	Default *TaskSpec           `yaml:"default" json:"default"`
+}
+
+// ExtractSpec describes a value read from the JSON response and stored into the parameters
+type ExtractSpec struct {
+	Path     string `yaml:"path" json:"path"`
+	Into     string `yaml:"into" json:"into"`
+	Type     string `yaml:"type" json:"type"`
+	Default  any    `yaml:"default" json:"default"`
+	Required bool   `yaml:"required" json:"required"`
+}
+
+const (
+	onErrorFailFast = "fail_fast"
+	onErrorContinue = "continue"
+)
+
+// Load reads a workflow file. Files ending in .json are parsed as JSON, anything else as YAML.
+// Environment variables referenced as ${VAR} are expanded before parsing; a bare $ is kept, as in the
+// template variables.
+func Load(path string) (*Definition, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow file: %w", err)
+	}
+	content := expandEnv(raw)
+
+	var def Definition
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&def)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&def)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse workflow file %s: %w", path, err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid workflow file %s: %w", path, err)
+	}
+	return &def, nil
+}
+
+// envVarRe matches the ${VAR} references to environment variables
+var envVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func expandEnv(raw []byte) []byte {
+	return envVarRe.ReplaceAllFunc(raw, func(ref []byte) []byte {
+		return []byte(os.Getenv(string(ref[2 : len(ref)-1])))
+	})
+}
+
+// Validate checks the definition structure before it is compiled
+func (d *Definition) Validate() error {
+	if d.Name == "" {
+		return errors.New("missing workflow name")
+	}
+	if len(d.Tasks) == 0 {
+		return errors.New("workflow has no tasks")
+	}
+	for _, task := range d.Tasks {
+		if err := task.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TaskSpec) validate() error {
+	if s.Name == "" {
+		return errors.New("task without name")
+	}
+	kinds := 0
+	if s.HTTP != nil {
+		kinds++
+	}
+	if s.Pipeline != nil {
+		kinds++
+	}
+	if s.Iterate != nil {
+		kinds++
+	}
+	if s.If != nil {
+		kinds++
+	}
+	if s.Switch != nil {
+		kinds++
+	}
+	if kinds != 1 {
+		return fmt.Errorf("task %q: exactly one of http, pipeline, iterate, if or switch must be set", s.Name)
+	}
+	if (s.Scoped || len(s.Publish) > 0) && s.Pipeline == nil {
+		return fmt.Errorf("task %q: scoped and publish only apply to pipelines", s.Name)
+	}
+
+	switch {
+	case s.HTTP != nil:
+		if s.HTTP.URL == "" {
+			return fmt.Errorf("task %q: missing url", s.Name)
+		}
+		templates := map[string]string{"url": s.HTTP.URL, "body": s.HTTP.Body}
+		if s.HTTP.Body != "" && len(s.HTTP.Form) > 0 {
+			return fmt.Errorf("task %q: body and form cannot be combined", s.Name)
+		}
+		for key, value := range s.HTTP.Headers {
+			templates["header "+key] = value
+		}
+		for key, value := range s.HTTP.Query {
+			templates["query "+key] = value
+		}
+		for key, value := range s.HTTP.Form {
+			templates["form field "+key] = value
+		}
+		for field, text := range templates {
+			if _, err := template.New(field).Parse(text); err != nil {
+				return fmt.Errorf("task %q: invalid %s template: %w", s.Name, field, err)
+			}
+		}
+		for _, rule := range s.HTTP.Extract {
+			if rule.Path == "" || rule.Into == "" {
+				return fmt.Errorf("task %q: extract rules need both path and into", s.Name)
+			}
+		}
+	case s.Pipeline != nil:
+		if len(s.Pipeline) == 0 {
+			return fmt.Errorf("task %q: empty pipeline", s.Name)
+		}
+		for _, task := range s.Pipeline {
+			if err := task.validate(); err != nil {
+				return err
+			}
+		}
+	case s.Iterate != nil:
+		if s.Iterate.Over == "" {
+			return fmt.Errorf("task %q: missing iterate.over", s.Name)
+		}
+		switch s.Iterate.OnError {
+		case "", onErrorFailFast, onErrorContinue:
+		default:
+			return fmt.Errorf("task %q: iterate.on_error must be %q or %q", s.Name, onErrorFailFast, onErrorContinue)
+		}
+		if s.Iterate.MaxFailures < 0 {
+			return fmt.Errorf("task %q: iterate.max_failures cannot be negative", s.Name)
+		}
+		if err := s.Iterate.Task.validate(); err != nil {
+			return err
+		}
+	case s.If != nil:
+		if s.If.Condition == "" {
+			return fmt.Errorf("task %q: missing if.condition", s.Name)
+		}
+		if _, err := template.New("condition").Parse(s.If.Condition); err != nil {
+			return fmt.Errorf("task %q: invalid condition template: %w", s.Name, err)
+		}
+		if err := s.If.Then.validate(); err != nil {
+			return err
+		}
+		if s.If.Else != nil {
+			if err := s.If.Else.validate(); err != nil {
+				return err
+			}
+		}
+	case s.Switch != nil:
+		if s.Switch.Value == "" {
+			return fmt.Errorf("task %q: missing switch.value", s.Name)
+		}
+		if _, err := template.New("value").Parse(s.Switch.Value); err != nil {
+			return fmt.Errorf("task %q: invalid value template: %w", s.Name, err)
+		}
+		if len(s.Switch.Cases) == 0 && s.Switch.Default == nil {
+			return fmt.Errorf("task %q: switch needs cases or a default", s.Name)
+		}
+		for _, task := range s.Switch.Cases {
+			if err := task.validate(); err != nil {
+				return err
+			}
+		}
+		if s.Switch.Default != nil {
+			if err := s.Switch.Default.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}